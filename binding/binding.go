@@ -0,0 +1,91 @@
+package binding
+
+import (
+	"net/http"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEPROTOBUF          = "application/x-protobuf"
+	MIMEYAML              = "application/yaml"
+	MIMEYAML2             = "application/x-yaml"
+	MIMETOML              = "application/toml"
+)
+
+// Binding 从请求中读取数据并填充到obj中
+type Binding interface {
+	Name() string
+	Bind(*http.Request, any) error
+}
+
+// BindingUri 从路由参数中读取数据并填充到obj中
+type BindingUri interface {
+	Name() string
+	BindUri(map[string][]string, any) error
+}
+
+// StructValidator 是绑定之后校验结构体的接口，可以替换为第三方的校验引擎
+type StructValidator interface {
+	// ValidateStruct 校验obj，obj可以是结构体、结构体指针以及由它们组成的slice
+	// 不需要校验的类型应该直接返回nil
+	ValidateStruct(any) error
+
+	// Engine 返回底层的校验引擎，用于注册自定义的规则
+	Engine() any
+}
+
+// Validator 是绑定之后使用的校验器，默认读取binding标签，为nil时不校验
+var Validator StructValidator = &defaultValidator{}
+
+var (
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Form          = formBinding{}
+	Query         = queryBinding{}
+	FormPost      = formPostBinding{}
+	FormMultipart = formMultipartBinding{}
+	Uri           = uriBinding{}
+	Header        = headerBinding{}
+)
+
+var (
+	_ Binding    = JSON
+	_ Binding    = XML
+	_ Binding    = Form
+	_ Binding    = Query
+	_ Binding    = FormPost
+	_ Binding    = FormMultipart
+	_ Binding    = Header
+	_ BindingUri = Uri
+)
+
+// Default 根据请求方法和Content-Type返回对应的Binding
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+
+	switch contentType {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	default: // case MIMEPOSTForm:
+		return Form
+	}
+}
+
+func validate(obj any) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.ValidateStruct(obj)
+}
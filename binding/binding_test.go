@@ -0,0 +1,159 @@
+package binding
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Page int `form:"page,default=1"`
+}
+
+type query struct {
+	inner
+	Name     string        `form:"name"`
+	Tags     []string      `form:"tag"`
+	Age      *int          `form:"age"`
+	Timeout  time.Duration `form:"timeout"`
+	Birthday time.Time     `form:"birthday" time_format:"2006-01-02" time_utc:"1"`
+	Ignored  string        `form:"-"`
+}
+
+func TestDefault(t *testing.T) {
+	cases := []struct {
+		method, contentType string
+		want                Binding
+	}{
+		{http.MethodGet, "", Form},
+		{http.MethodGet, MIMEJSON, Form},
+		{http.MethodPost, MIMEJSON, JSON},
+		{http.MethodPut, MIMEXML, XML},
+		{http.MethodPost, MIMEXML2, XML},
+		{http.MethodPost, MIMEPOSTForm, Form},
+		{http.MethodPost, MIMEMultipartPOSTForm, FormMultipart},
+	}
+	for _, c := range cases {
+		if got := Default(c.method, c.contentType); got != c.want {
+			t.Errorf("Default(%s, %s) = %s, want %s", c.method, c.contentType, got.Name(), c.want.Name())
+		}
+	}
+}
+
+func TestQueryBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet,
+		"/?name=rough&tag=a&tag=b&age=3&timeout=1s&birthday=2023-12-18&Ignored=x", nil)
+	var q query
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != "rough" || len(q.Tags) != 2 || q.Age == nil || *q.Age != 3 ||
+		q.Timeout != time.Second || q.Page != 1 || q.Ignored != "" ||
+		!q.Birthday.Equal(time.Date(2023, 12, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected result: %+v", q)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?age=x", nil)
+	if err := Query.Bind(req, &q); err == nil {
+		t.Error("expected error for invalid int")
+	}
+}
+
+func TestJSONBinding(t *testing.T) {
+	var obj struct {
+		Name string `json:"name"`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"rough"}`))
+	if err := JSON.Bind(req, &obj); err != nil || obj.Name != "rough" {
+		t.Errorf("got %+v, %v", obj, err)
+	}
+}
+
+func TestXMLBinding(t *testing.T) {
+	var obj struct {
+		Name string `xml:"name"`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<root><name>rough</name></root>`))
+	if err := XML.Bind(req, &obj); err != nil || obj.Name != "rough" {
+		t.Errorf("got %+v, %v", obj, err)
+	}
+}
+
+func TestHeaderBinding(t *testing.T) {
+	var obj struct {
+		Token string `header:"x-token"`
+		Limit int    `header:"X-Limit"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Token", "abc")
+	req.Header.Set("X-Limit", "10")
+	if err := Header.Bind(req, &obj); err != nil || obj.Token != "abc" || obj.Limit != 10 {
+		t.Errorf("got %+v, %v", obj, err)
+	}
+}
+
+func TestUriBinding(t *testing.T) {
+	var obj struct {
+		ID int `uri:"id"`
+	}
+	if err := Uri.BindUri(map[string][]string{"id": {"7"}}, &obj); err != nil || obj.ID != 7 {
+		t.Errorf("got %+v, %v", obj, err)
+	}
+}
+
+func TestFormMultipartBinding(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("name", "rough")
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	_, _ = fw.Write([]byte("hello"))
+	fw, _ = mw.CreateFormFile("files", "b.txt")
+	_, _ = fw.Write([]byte("world"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var obj struct {
+		Name  string                  `form:"name"`
+		File  *multipart.FileHeader   `form:"file"`
+		Files []*multipart.FileHeader `form:"files"`
+	}
+	if err := FormMultipart.Bind(req, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Name != "rough" || obj.File == nil || obj.File.Filename != "a.txt" ||
+		len(obj.Files) != 1 || obj.Files[0].Filename != "b.txt" {
+		t.Errorf("unexpected result: %+v", obj)
+	}
+}
+
+func TestNestedFormKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet,
+		"/?filter[status]=open&filter[owner]=me&ids[1]=20&ids[0]=10&names[]=a&names[]=b&counts[x]=1", nil)
+	var obj struct {
+		Filter map[string]string `form:"filter"`
+		IDs    []int             `form:"ids"`
+		Names  []string          `form:"names"`
+		Counts map[string]int    `form:"counts"`
+	}
+	if err := Query.Bind(req, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Filter) != 2 || obj.Filter["status"] != "open" || obj.Filter["owner"] != "me" {
+		t.Errorf("Filter = %v", obj.Filter)
+	}
+	if len(obj.IDs) != 2 || obj.IDs[0] != 10 || obj.IDs[1] != 20 {
+		t.Errorf("IDs = %v", obj.IDs)
+	}
+	if len(obj.Names) != 2 || obj.Names[1] != "b" {
+		t.Errorf("Names = %v", obj.Names)
+	}
+	if obj.Counts["x"] != 1 {
+		t.Errorf("Counts = %v", obj.Counts)
+	}
+}
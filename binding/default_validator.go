@@ -0,0 +1,335 @@
+package binding
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationFunc 校验value是否满足规则，param为标签中等号后面的参数
+type ValidationFunc func(value reflect.Value, param string) bool
+
+// FieldError 是一个字段的校验错误
+type FieldError struct {
+	// Field 是字段的路径，如"Items[0].Name"
+	Field string
+	// Tag 是没有通过的规则，如"required"、"min"
+	Tag string
+	// Param 是规则的参数，如"min=1"中的"1"
+	Param string
+	// Value 是字段的值
+	Value any
+}
+
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field '%s' failed on the '%s' tag", e.Field, e.Tag)
+	}
+	return fmt.Sprintf("field '%s' failed on the '%s=%s' tag", e.Field, e.Tag, e.Param)
+}
+
+// ValidationErrors 是defaultValidator返回的错误
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, e := range ve {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate 是默认的校验引擎，通过Validator.Engine()获取
+type Validate struct {
+	tagName string
+
+	mu    sync.RWMutex
+	rules map[string]ValidationFunc
+}
+
+type defaultValidator struct {
+	once     sync.Once
+	validate *Validate
+}
+
+var _ StructValidator = (*defaultValidator)(nil)
+
+// ValidateStruct 校验obj，obj可以是结构体、结构体指针或者由它们组成的slice、array
+func (v *defaultValidator) ValidateStruct(obj any) error {
+	if obj == nil {
+		return nil
+	}
+	v.lazyinit()
+	return v.validate.Struct(obj)
+}
+
+// Engine 返回*Validate，可以用来注册自定义的规则
+func (v *defaultValidator) Engine() any {
+	v.lazyinit()
+	return v.validate
+}
+
+func (v *defaultValidator) lazyinit() {
+	v.once.Do(func() {
+		v.validate = NewValidate("binding")
+	})
+}
+
+// NewValidate 返回一个读取tagName标签的校验引擎
+func NewValidate(tagName string) *Validate {
+	return &Validate{
+		tagName: tagName,
+		rules: map[string]ValidationFunc{
+			"required": hasValue,
+			"min":      isGte,
+			"max":      isLte,
+			"len":      hasLen,
+			"email":    isEmail,
+			"oneof":    isOneOf,
+		},
+	}
+}
+
+// RegisterValidation 注册一个规则，已存在的规则会被覆盖
+func (v *Validate) RegisterValidation(tag string, fn ValidationFunc) error {
+	if tag == "" || tag == "omitempty" || strings.ContainsAny(tag, ",=") {
+		return fmt.Errorf("binding: invalid validation tag %q", tag)
+	}
+	if fn == nil {
+		return fmt.Errorf("binding: validation function for %q is nil", tag)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[tag] = fn
+	return nil
+}
+
+func (v *Validate) rule(tag string) ValidationFunc {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	fn, ok := v.rules[tag]
+	if !ok {
+		panic("binding: undefined validation tag: " + tag)
+	}
+	return fn
+}
+
+// Struct 校验obj，有字段没有通过时返回ValidationErrors
+func (v *Validate) Struct(obj any) error {
+	var errs ValidationErrors
+	v.validateValue(reflect.ValueOf(obj), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateValue 递归地进入结构体、指针、slice、array和map，校验其中的结构体字段
+func (v *Validate) validateValue(value reflect.Value, path string, errs *ValidationErrors) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			v.validateValue(value.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return
+		}
+		typ := value.Type()
+		for i := 0; i < value.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+			fieldPath := sf.Name
+			if sf.Anonymous {
+				fieldPath = ""
+			}
+			fieldPath = joinPath(path, fieldPath)
+
+			field := value.Field(i)
+			tag := sf.Tag.Get(v.tagName)
+			if tag == "-" {
+				continue
+			}
+			if tag != "" && !v.validateField(field, fieldPath, tag, errs) {
+				continue
+			}
+			v.validateValue(field, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			v.validateValue(value.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			v.validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), errs)
+		}
+	}
+}
+
+// validateField 按照tag中的规则校验field，没有通过时返回false，不再校验其内部
+func (v *Validate) validateField(field reflect.Value, path, tag string, errs *ValidationErrors) bool {
+	rules := strings.Split(tag, ",")
+	for _, r := range rules {
+		if r == "omitempty" {
+			if !hasValue(field, "") {
+				return false
+			}
+			continue
+		}
+		name, param, _ := strings.Cut(r, "=")
+		if !v.rule(name)(field, param) {
+			var val any
+			if field.CanInterface() {
+				val = field.Interface()
+			}
+			*errs = append(*errs, FieldError{Field: path, Tag: name, Param: param, Value: val})
+			return false
+		}
+	}
+	return true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
+
+func hasValue(value reflect.Value, _ string) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() > 0
+	case reflect.Pointer, reflect.Interface, reflect.Chan, reflect.Func:
+		return !value.IsNil()
+	case reflect.Invalid:
+		return false
+	default:
+		return !value.IsZero()
+	}
+}
+
+// indirect 解开value外层的指针，遇到nil指针时返回false
+func indirect(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+	return value, true
+}
+
+// compare 比较value和param，字符串、slice、map和array比较长度，数字比较大小
+func compare(value reflect.Value, param string) (int, bool) {
+	value, ok := indirect(value)
+	if !ok {
+		return 0, false
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return compareInt(int64(utf8.RuneCountInString(value.String())), param)
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return compareInt(int64(value.Len()), param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == durationType {
+			d, err := time.ParseDuration(param)
+			if err != nil {
+				panic("binding: invalid duration param " + param)
+			}
+			return compareInt(value.Int(), strconv.FormatInt(int64(d), 10))
+		}
+		return compareInt(value.Int(), param)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			panic("binding: invalid uint param " + param)
+		}
+		return cmp(value.Uint(), p), true
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic("binding: invalid float param " + param)
+		}
+		return cmp(value.Float(), p), true
+	}
+	return 0, false
+}
+
+func compareInt(n int64, param string) (int, bool) {
+	p, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		panic("binding: invalid int param " + param)
+	}
+	return cmp(n, p), true
+}
+
+func cmp[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isGte(value reflect.Value, param string) bool {
+	c, ok := compare(value, param)
+	return ok && c >= 0
+}
+
+func isLte(value reflect.Value, param string) bool {
+	c, ok := compare(value, param)
+	return ok && c <= 0
+}
+
+func hasLen(value reflect.Value, param string) bool {
+	c, ok := compare(value, param)
+	return ok && c == 0
+}
+
+func isEmail(value reflect.Value, _ string) bool {
+	value, ok := indirect(value)
+	if !ok || value.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(value.String())
+	return err == nil && addr.Address == value.String()
+}
+
+func isOneOf(value reflect.Value, param string) bool {
+	value, ok := indirect(value)
+	if !ok {
+		return false
+	}
+	var s string
+	switch value.Kind() {
+	case reflect.String:
+		s = value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(value.Uint(), 10)
+	default:
+		return false
+	}
+	for _, v := range strings.Fields(param) {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `binding:"required"`
+}
+
+type account struct {
+	Name      string   `binding:"required,min=1,max=8"`
+	Email     string   `binding:"omitempty,email"`
+	Role      string   `binding:"oneof=admin user"`
+	Age       int      `binding:"min=0,max=150"`
+	Tags      []string `binding:"max=2"`
+	Address   address
+	Addresses []address `binding:"required"`
+	Backup    *address
+}
+
+func TestValidateStruct(t *testing.T) {
+	ok := account{
+		Name:      "rough",
+		Role:      "user",
+		Addresses: []address{{City: "a"}},
+	}
+	ok.Address.City = "b"
+	if err := Validator.ValidateStruct(&ok); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	bad := account{
+		Name:      "too long name",
+		Email:     "not an email",
+		Role:      "guest",
+		Age:       200,
+		Tags:      []string{"a", "b", "c"},
+		Addresses: []address{{City: "a"}, {}},
+		Backup:    &address{},
+	}
+	err := Validator.ValidateStruct(&bad)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Field + ":" + e.Tag
+	}
+	want := []string{
+		"Name:max", "Email:email", "Role:oneof", "Age:max", "Tags:max",
+		"Address.City:required", "Addresses[1].City:required", "Backup.City:required",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestValidatePointerFields(t *testing.T) {
+	type profile struct {
+		Email *string `binding:"omitempty,email"`
+		Role  *string `binding:"omitempty,oneof=admin user"`
+		Level *int    `binding:"omitempty,oneof=1 2"`
+	}
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	if err := Validator.ValidateStruct(&profile{}); err != nil {
+		t.Errorf("nil pointers: %v", err)
+	}
+	ok := profile{Email: str("x@y.com"), Role: str("admin"), Level: num(2)}
+	if err := Validator.ValidateStruct(&ok); err != nil {
+		t.Errorf("valid pointers: %v", err)
+	}
+
+	bad := profile{Email: str("nope"), Role: str("guest"), Level: num(3)}
+	var errs ValidationErrors
+	if err := Validator.ValidateStruct(&bad); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Errorf("invalid pointers: %v", err)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	v := Validator.Engine().(*Validate)
+	err := v.RegisterValidation("even", func(value reflect.Value, _ string) bool {
+		return value.Int()%2 == 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obj struct {
+		N int `form:"n" binding:"even"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/?n=3", nil)
+	if err := Query.Bind(req, &obj); err == nil {
+		t.Error("expected validation error")
+	}
+	req = httptest.NewRequest(http.MethodGet, "/?n=4", nil)
+	if err := Query.Bind(req, &obj); err != nil {
+		t.Error(err)
+	}
+}
+
+type noopValidator struct{}
+
+func (noopValidator) ValidateStruct(any) error { return nil }
+func (noopValidator) Engine() any              { return nil }
+
+func TestSwapValidator(t *testing.T) {
+	old := Validator
+	Validator = noopValidator{}
+	defer func() { Validator = old }()
+
+	var obj struct {
+		Name string `form:"name" binding:"required"`
+	}
+	if err := Query.Bind(httptest.NewRequest(http.MethodGet, "/", nil), &obj); err != nil {
+		t.Error(err)
+	}
+}
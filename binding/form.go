@@ -0,0 +1,86 @@
+package binding
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+// defaultMemory 是直接使用binding时解析multipart表单的内存上限，通过Context绑定时使用Engine.MaxMultipartMemory
+const defaultMemory = 32 << 20
+
+type formBinding struct{}
+type formPostBinding struct{}
+type formMultipartBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind 同时读取query和body中的表单，body可以是multipart
+func (formBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	if err := mapForm(obj, req.Form); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func (formPostBinding) Name() string {
+	return "form-urlencoded"
+}
+
+// Bind 只读取body中的表单
+func (formPostBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := mapForm(obj, req.PostForm); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind 读取multipart表单，*multipart.FileHeader和[]*multipart.FileHeader类型的字段会填充上传的文件
+func (formMultipartBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil {
+		return err
+	}
+	if err := mappingByPtr(obj, (*multipartSource)(req), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type multipartSource http.Request
+
+var (
+	multipartFileHeaderType      = reflect.TypeOf(multipart.FileHeader{})
+	multipartFileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// TrySet 优先从上传的文件中查找key，其次是表单中的值
+func (r *multipartSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	if files := r.MultipartForm.File[key]; len(files) > 0 {
+		switch {
+		case value.Type() == multipartFileHeaderSliceType:
+			value.Set(reflect.ValueOf(files))
+			return true, nil
+		case value.Type() == multipartFileHeaderType:
+			// *multipart.FileHeader类型的字段由mapping分配，这里只需要填充结构体
+			value.Set(reflect.ValueOf(*files[0]))
+			return true, nil
+		}
+	}
+	return setByForm(value, field, r.MultipartForm.Value, key, opt)
+}
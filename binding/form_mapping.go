@@ -0,0 +1,445 @@
+package binding
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUnknownType = errors.New("unknown type")
+
+	emptyField = reflect.StructField{}
+
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setter 从数据源中读取key对应的值并写入value
+type setter interface {
+	TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSet bool, err error)
+}
+
+type setOptions struct {
+	isDefaultExists bool
+	defaultValue    string
+}
+
+type formSource map[string][]string
+
+func (form formSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	return setByForm(value, field, form, key, opt)
+}
+
+func mapForm(ptr any, form map[string][]string) error {
+	return mapFormByTag(ptr, form, "form")
+}
+
+func mapFormByTag(ptr any, form map[string][]string, tag string) error {
+	return mappingByPtr(ptr, formSource(form), tag)
+}
+
+func mappingByPtr(ptr any, setter setter, tag string) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return errors.New("binding: obj must be a non-nil pointer")
+	}
+	_, err := mapping(value, emptyField, setter, tag)
+	return err
+}
+
+// mapping 按照字段的tag递归地填充value，返回是否设置了任意字段
+func mapping(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
+	if field.Tag.Get(tag) == "-" {
+		return false, nil
+	}
+
+	vKind := value.Kind()
+
+	if vKind == reflect.Pointer {
+		isNew := false
+		vPtr := value
+		if value.IsNil() {
+			isNew = true
+			vPtr = reflect.New(value.Type().Elem())
+		}
+		isSet, err := mapping(vPtr.Elem(), field, setter, tag)
+		if err != nil {
+			return false, err
+		}
+		if isNew && isSet {
+			value.Set(vPtr)
+		}
+		return isSet, nil
+	}
+
+	if vKind != reflect.Struct || !field.Anonymous {
+		ok, err := tryToSetValue(value, field, setter, tag)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	if vKind == reflect.Struct {
+		tValue := value.Type()
+
+		isSet := false
+		for i := 0; i < value.NumField(); i++ {
+			sf := tValue.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous { // 未导出的字段
+				continue
+			}
+			ok, err := mapping(value.Field(i), sf, setter, tag)
+			if err != nil {
+				return false, err
+			}
+			isSet = isSet || ok
+		}
+		return isSet, nil
+	}
+	return false, nil
+}
+
+func tryToSetValue(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
+	if field.Name == "" { // 最外层的obj
+		return false, nil
+	}
+
+	var opt setOptions
+	tagValue := field.Tag.Get(tag)
+	tagValue, opts := head(tagValue, ",")
+	if tagValue == "" {
+		tagValue = field.Name
+	}
+
+	for opts != "" {
+		var opt0 string
+		opt0, opts = head(opts, ",")
+		if k, v := head(opt0, "="); k == "default" {
+			opt.isDefaultExists = true
+			opt.defaultValue = v
+		}
+	}
+
+	return setter.TrySet(value, field, tagValue, opt)
+}
+
+func setByForm(value reflect.Value, field reflect.StructField, form map[string][]string, key string, opt setOptions) (isSet bool, err error) {
+	vs, ok := form[key]
+	if !ok {
+		// 支持key[sub]=value形式的map和key[]=value、key[0]=value形式的slice
+		switch value.Kind() {
+		case reflect.Map:
+			if isSet, err = setFormMap(value, field, form, key); isSet || err != nil {
+				return isSet, err
+			}
+		case reflect.Slice:
+			vs, ok = nestedSlice(form, key)
+		}
+	}
+	if !ok && !opt.isDefaultExists {
+		return false, nil
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		if !ok {
+			vs = []string{opt.defaultValue}
+		}
+		return true, setSlice(vs, value, field)
+	case reflect.Array:
+		if !ok {
+			vs = []string{opt.defaultValue}
+		}
+		if len(vs) != value.Len() {
+			return false, fmt.Errorf("%q is not valid value for %s", vs, value.Type().String())
+		}
+		return true, setArray(vs, value, field)
+	default:
+		var val string
+		if !ok {
+			val = opt.defaultValue
+		}
+		if len(vs) > 0 {
+			val = vs[0]
+		}
+		return true, setWithProperType(val, value, field)
+	}
+}
+
+// setFormMap 将form中所有key[sub]形式的参数填充到map类型的value中
+func setFormMap(value reflect.Value, field reflect.StructField, form map[string][]string, key string) (bool, error) {
+	typ := value.Type()
+	if typ.Key().Kind() != reflect.String {
+		return false, nil
+	}
+
+	var m reflect.Value
+	for k, vals := range form {
+		sub, ok := bracketKey(k, key)
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if !m.IsValid() {
+			m = value
+			if m.IsNil() {
+				m = reflect.MakeMap(typ)
+			}
+		}
+		elem := reflect.New(typ.Elem()).Elem()
+		var err error
+		if elem.Kind() == reflect.Slice {
+			err = setSlice(vals, elem, field)
+		} else {
+			err = setWithProperType(vals[0], elem, field)
+		}
+		if err != nil {
+			return false, err
+		}
+		m.SetMapIndex(reflect.ValueOf(sub).Convert(typ.Key()), elem)
+	}
+	if !m.IsValid() {
+		return false, nil
+	}
+	value.Set(m)
+	return true, nil
+}
+
+// nestedSlice 读取key[]=value或者key[0]=value形式的参数，后者按下标排序
+func nestedSlice(form map[string][]string, key string) ([]string, bool) {
+	if vs, ok := form[key+"[]"]; ok {
+		return vs, true
+	}
+
+	type indexed struct {
+		index int
+		value string
+	}
+	var items []indexed
+	for k, vals := range form {
+		sub, ok := bracketKey(k, key)
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		index, err := strconv.Atoi(sub)
+		if err != nil || index < 0 {
+			continue
+		}
+		items = append(items, indexed{index, vals[0]})
+	}
+	if len(items) == 0 {
+		return nil, false
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].index < items[j].index })
+	vs := make([]string, len(items))
+	for i, item := range items {
+		vs[i] = item.value
+	}
+	return vs, true
+}
+
+// bracketKey 当k为key[sub]时返回sub
+func bracketKey(k, key string) (string, bool) {
+	if len(k) < len(key)+3 || k[:len(key)] != key || k[len(key)] != '[' || k[len(k)-1] != ']' {
+		return "", false
+	}
+	sub := k[len(key)+1 : len(k)-1]
+	if strings.ContainsAny(sub, "[]") {
+		return "", false
+	}
+	return sub, true
+}
+
+func setWithProperType(val string, value reflect.Value, field reflect.StructField) error {
+	// time.Time按照time_format等标签单独处理
+	if value.Kind() != reflect.Pointer && value.Type() != timeType &&
+		value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch value.Kind() {
+	case reflect.Int64:
+		if value.Type() == durationType {
+			return setTimeDuration(val, value)
+		}
+		return setIntField(val, 64, value)
+	case reflect.Int:
+		return setIntField(val, 0, value)
+	case reflect.Int8:
+		return setIntField(val, 8, value)
+	case reflect.Int16:
+		return setIntField(val, 16, value)
+	case reflect.Int32:
+		return setIntField(val, 32, value)
+	case reflect.Uint:
+		return setUintField(val, 0, value)
+	case reflect.Uint8:
+		return setUintField(val, 8, value)
+	case reflect.Uint16:
+		return setUintField(val, 16, value)
+	case reflect.Uint32:
+		return setUintField(val, 32, value)
+	case reflect.Uint64:
+		return setUintField(val, 64, value)
+	case reflect.Bool:
+		return setBoolField(val, value)
+	case reflect.Float32:
+		return setFloatField(val, 32, value)
+	case reflect.Float64:
+		return setFloatField(val, 64, value)
+	case reflect.String:
+		value.SetString(val)
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return setTimeField(val, field, value)
+		}
+		return json.Unmarshal([]byte(val), value.Addr().Interface())
+	case reflect.Map:
+		return json.Unmarshal([]byte(val), value.Addr().Interface())
+	case reflect.Pointer:
+		if !value.Elem().IsValid() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setWithProperType(val, value.Elem(), field)
+	default:
+		return errUnknownType
+	}
+	return nil
+}
+
+func setIntField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	intVal, err := strconv.ParseInt(val, 10, bitSize)
+	if err == nil {
+		field.SetInt(intVal)
+	}
+	return err
+}
+
+func setUintField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	uintVal, err := strconv.ParseUint(val, 10, bitSize)
+	if err == nil {
+		field.SetUint(uintVal)
+	}
+	return err
+}
+
+func setBoolField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "false"
+	}
+	boolVal, err := strconv.ParseBool(val)
+	if err == nil {
+		field.SetBool(boolVal)
+	}
+	return err
+}
+
+func setFloatField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0.0"
+	}
+	floatVal, err := strconv.ParseFloat(val, bitSize)
+	if err == nil {
+		field.SetFloat(floatVal)
+	}
+	return err
+}
+
+// setTimeField 按照time_format标签解析时间，默认为RFC3339
+// time_format为unix或unixnano时按时间戳解析，time_utc和time_location指定时区
+func setTimeField(val string, structField reflect.StructField, value reflect.Value) error {
+	timeFormat := structField.Tag.Get("time_format")
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
+	switch tf := strings.ToLower(timeFormat); tf {
+	case "unix", "unixnano":
+		tv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		d := time.Duration(1)
+		if tf == "unixnano" {
+			d = time.Second
+		}
+		t := time.Unix(tv/int64(d), tv%int64(d))
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if val == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	l := time.Local
+	if isUTC, _ := strconv.ParseBool(structField.Tag.Get("time_utc")); isUTC {
+		l = time.UTC
+	}
+	if locTag := structField.Tag.Get("time_location"); locTag != "" {
+		loc, err := time.LoadLocation(locTag)
+		if err != nil {
+			return err
+		}
+		l = loc
+	}
+
+	t, err := time.ParseInLocation(timeFormat, val, l)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func setArray(vals []string, value reflect.Value, field reflect.StructField) error {
+	for i, s := range vals {
+		if err := setWithProperType(s, value.Index(i), field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setSlice(vals []string, value reflect.Value, field reflect.StructField) error {
+	slice := reflect.MakeSlice(value.Type(), len(vals), len(vals))
+	if err := setArray(vals, slice, field); err != nil {
+		return err
+	}
+	value.Set(slice)
+	return nil
+}
+
+func setTimeDuration(val string, value reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(d))
+	return nil
+}
+
+func head(str, sep string) (head string, tail string) {
+	head, tail, _ = strings.Cut(str, sep)
+	return head, tail
+}
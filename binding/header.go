@@ -0,0 +1,27 @@
+package binding
+
+import (
+	"net/http"
+	"net/textproto"
+	"reflect"
+)
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+// Bind 读取请求头，字段使用header标签，key不区分大小写
+func (headerBinding) Bind(req *http.Request, obj any) error {
+	if err := mappingByPtr(obj, headerSource(req.Header), "header"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type headerSource map[string][]string
+
+func (hs headerSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	return setByForm(value, field, hs, textproto.CanonicalMIMEHeaderKey(key), opt)
+}
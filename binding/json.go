@@ -0,0 +1,41 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// EnableDecoderUseNumber 为true时，JSON中的数字会解析为json.Number而不是float64
+var EnableDecoderUseNumber = false
+
+// EnableDecoderDisallowUnknownFields 为true时，JSON中出现obj没有的字段会返回错误
+var EnableDecoderDisallowUnknownFields = false
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+func (jsonBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := decodeJSON(req.Body, obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func decodeJSON(r io.Reader, obj any) error {
+	decoder := json.NewDecoder(r)
+	if EnableDecoderUseNumber {
+		decoder.UseNumber()
+	}
+	if EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(obj)
+}
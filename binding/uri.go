@@ -0,0 +1,15 @@
+package binding
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+// BindUri 读取路由参数，字段使用uri标签
+func (uriBinding) BindUri(m map[string][]string, obj any) error {
+	if err := mapFormByTag(obj, m, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -1,13 +1,24 @@
 package rough
 
 import (
+	"context"
 	"errors"
-	"log"
+	"io"
+	"log/slog"
 	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/cainmusic/rough/binding"
 	"github.com/cainmusic/rough/render"
+	"github.com/cainmusic/rough/websocket"
 )
 
 const abortIndex int8 = math.MaxInt8 >> 1
@@ -15,8 +26,13 @@ const abortIndex int8 = math.MaxInt8 >> 1
 type Context struct {
 	engine *Engine
 
-	W    http.ResponseWriter
-	R    *http.Request
+	writermem responseWriter
+
+	W ResponseWriter
+	R *http.Request
+
+	// Keys 保存当前请求中通过Set设置的值，由mu保护
+	mu   sync.RWMutex
 	Keys map[string]any
 
 	Params       Params
@@ -27,10 +43,66 @@ type Context struct {
 	handlers []HandleFunc
 	fullPath string
 
+	// Errors 是处理请求过程中通过Error收集到的错误
+	Errors errorMsgs
+
 	queryCache url.Values
 	formCache  url.Values
 
-	statusCode int
+	logger *slog.Logger
+
+	sameSite http.SameSite
+
+	// bodyTooLarge 表示读取请求体时超出了Engine.MaxRequestBodySize
+	bodyTooLarge bool
+}
+
+// reset 清空上一次请求留下的状态，Context从engine的池中取出后调用
+func (c *Context) reset() {
+	c.W = &c.writermem
+	c.Keys = nil
+	c.Params = c.Params[:0]
+	// Context创建之后可能又注册了参数更多的路由，容量不足时重新分配
+	if cap(*c.params) < int(c.engine.maxParams) {
+		*c.params = make(Params, 0, c.engine.maxParams)
+	}
+	*c.params = (*c.params)[:0]
+	if cap(*c.skippedNodes) < int(c.engine.maxSections) {
+		*c.skippedNodes = make([]skippedNode, 0, c.engine.maxSections)
+	}
+	*c.skippedNodes = (*c.skippedNodes)[:0]
+	c.index = -1
+	c.handlers = nil
+	c.Errors = c.Errors[:0]
+	c.fullPath = ""
+	c.queryCache = nil
+	c.formCache = nil
+	c.logger = nil
+	c.sameSite = 0
+	c.bodyTooLarge = false
+}
+
+// ContextKey 是Context在Value中对应的key，c.Value(ContextKey)返回c本身
+const ContextKey = "_cainmusic/rough/contextkey"
+
+var _ context.Context = (*Context)(nil)
+
+// RequestIDHeader 是Context.Logger读取请求ID的请求头
+const RequestIDHeader = "X-Request-ID"
+
+// Logger 返回当前请求的日志，带有请求ID、方法和匹配到的路由
+func (c *Context) Logger() *slog.Logger {
+	if c.logger == nil {
+		attrs := make([]any, 0, 3)
+		if id := c.R.Header.Get(RequestIDHeader); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		attrs = append(attrs,
+			slog.String("method", c.R.Method),
+			slog.String("route", c.fullPath))
+		c.logger = c.engine.logger().With(attrs...)
+	}
+	return c.logger
 }
 
 func (c *Context) Param(key string) string {
@@ -42,6 +114,14 @@ func (c *Context) Query(key string) string {
 	return value
 }
 
+// DefaultQuery 返回key对应的query参数，不存在时返回defaultValue
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func (c *Context) GetQuery(key string) (string, bool) {
 	if values, ok := c.GetQueryArray(key); ok {
 		return values[0], ok
@@ -70,11 +150,31 @@ func (c *Context) GetQueryArray(key string) ([]string, bool) {
 	return values, ok
 }
 
+// QueryMap 返回形如key[sub]=value的query参数组成的map
+func (c *Context) QueryMap(key string) map[string]string {
+	dicts, _ := c.GetQueryMap(key)
+	return dicts
+}
+
+// GetQueryMap 返回形如key[sub]=value的query参数组成的map，以及是否至少存在一个
+func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
+	c.initQueryCache()
+	return getMap(c.queryCache, key)
+}
+
 func (c *Context) PostForm(key string) string {
 	value, _ := c.GetPostForm(key)
 	return value
 }
 
+// DefaultPostForm 返回key对应的表单参数，不存在时返回defaultValue
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func (c *Context) GetPostForm(key string) (string, bool) {
 	if values, ok := c.GetPostFormArray(key); ok {
 		return values[0], ok
@@ -90,10 +190,13 @@ func (c *Context) PostFormArray(key string) []string {
 func (c *Context) initFormCache() {
 	if c.formCache == nil {
 		c.formCache = make(url.Values)
-		if err := c.R.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		if err := c.R.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
 			// 无视"request Content-Type isn't multipart/form-data"的报错
+			if c.abortBodyTooLarge(err) {
+				return
+			}
 			if !errors.Is(err, http.ErrNotMultipart) {
-				log.Println("form parse error", err)
+				c.Logger().Warn("form parse error", slog.Any("error", err))
 			}
 		}
 		c.formCache = c.R.PostForm
@@ -106,6 +209,314 @@ func (c *Context) GetPostFormArray(key string) ([]string, bool) {
 	return values, ok
 }
 
+// PostFormMap 返回形如key[sub]=value的表单参数组成的map
+func (c *Context) PostFormMap(key string) map[string]string {
+	dicts, _ := c.GetPostFormMap(key)
+	return dicts
+}
+
+// GetPostFormMap 返回形如key[sub]=value的表单参数组成的map，以及是否至少存在一个
+func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
+	c.initFormCache()
+	return getMap(c.formCache, key)
+}
+
+// getMap 从m中找出所有形如key[sub]的参数，返回sub到第一个值的map
+func getMap(m map[string][]string, key string) (map[string]string, bool) {
+	dicts := make(map[string]string)
+	exist := false
+	for k, v := range m {
+		if i := strings.IndexByte(k, '['); i >= 1 && k[0:i] == key {
+			if j := strings.IndexByte(k[i+1:], ']'); j >= 1 {
+				exist = true
+				dicts[k[i+1:][:j]] = v[0]
+			}
+		}
+	}
+	return dicts, exist
+}
+
+// FormFile 返回name对应的第一个上传文件
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.R.MultipartForm == nil {
+		if err := c.R.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+			c.abortBodyTooLarge(err)
+			return nil, err
+		}
+	}
+	f, fh, err := c.R.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, err
+}
+
+// MultipartForm 返回解析后的multipart表单，包括上传的文件
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	err := c.R.ParseMultipartForm(c.engine.MaxMultipartMemory)
+	if err != nil {
+		c.abortBodyTooLarge(err)
+	}
+	return c.R.MultipartForm, err
+}
+
+// maxBytesBody 在请求体超出Engine.MaxRequestBodySize时记录到Context
+type maxBytesBody struct {
+	io.ReadCloser
+	c *Context
+}
+
+func (b maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if err != nil && errors.As(err, &maxBytesErr) {
+		b.c.bodyTooLarge = true
+	}
+	return n, err
+}
+
+// abortBodyTooLarge 在请求体超出限制时记录err并以413终止请求，返回是否已经终止
+func (c *Context) abortBodyTooLarge(err error) bool {
+	if !c.bodyTooLarge {
+		return false
+	}
+	_ = c.Error(err)
+	c.Abort()
+	c.writeBodyTooLarge()
+	return true
+}
+
+// writeBodyTooLarge 请求体超出限制且还没有写出响应时写出413，之后的Render都会被忽略
+func (c *Context) writeBodyTooLarge() {
+	if !c.bodyTooLarge || c.W.Written() {
+		return
+	}
+	c.W.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.W.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = c.W.Write(default413Body)
+}
+
+// SaveUploadedFile 将上传的文件保存到dst，dst所在的目录不存在时会自动创建
+// dst中不能包含".."；文件名来自客户端时应使用SaveUploadedFileTo
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	if containsDotDot(dst) {
+		return errors.New("invalid dst path: " + dst)
+	}
+	return saveUploadedFile(file, dst)
+}
+
+// SaveUploadedFileTo 将上传的文件保存到dir下的name，目录不存在时会自动创建
+// name必须是dir内的相对路径（见filepath.IsLocal），避免使用客户端传来的文件名时写到dir之外
+func (c *Context) SaveUploadedFileTo(file *multipart.FileHeader, dir, name string) error {
+	if !filepath.IsLocal(name) {
+		return errors.New("invalid upload file name: " + name)
+	}
+	return saveUploadedFile(file, filepath.Join(dir, name))
+}
+
+func saveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// Bind 根据请求方法和Content-Type选择binding，失败时以400终止请求
+func (c *Context) Bind(obj any) error {
+	b := binding.Default(c.R.Method, c.ContentType())
+	return c.MustBindWith(obj, b)
+}
+
+// BindJSON 是c.MustBindWith(obj, binding.JSON)的简写
+func (c *Context) BindJSON(obj any) error {
+	return c.MustBindWith(obj, binding.JSON)
+}
+
+// BindXML 是c.MustBindWith(obj, binding.XML)的简写
+func (c *Context) BindXML(obj any) error {
+	return c.MustBindWith(obj, binding.XML)
+}
+
+// BindQuery 是c.MustBindWith(obj, binding.Query)的简写
+func (c *Context) BindQuery(obj any) error {
+	return c.MustBindWith(obj, binding.Query)
+}
+
+// BindHeader 是c.MustBindWith(obj, binding.Header)的简写
+func (c *Context) BindHeader(obj any) error {
+	return c.MustBindWith(obj, binding.Header)
+}
+
+// BindUri 使用binding.Uri绑定路由参数，失败时以400终止请求
+func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// MustBindWith 使用指定的binding，失败时以400终止请求并记录ErrorTypeBind类型的错误
+// 请求体超出Engine.MaxRequestBodySize时以413终止请求
+func (c *Context) MustBindWith(obj any, b binding.Binding) error {
+	if err := c.ShouldBindWith(obj, b); err != nil {
+		code := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithError(code, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// ShouldBind 根据请求方法和Content-Type选择binding，失败时只返回错误
+func (c *Context) ShouldBind(obj any) error {
+	b := binding.Default(c.R.Method, c.ContentType())
+	return c.ShouldBindWith(obj, b)
+}
+
+// ShouldBindJSON 是c.ShouldBindWith(obj, binding.JSON)的简写
+func (c *Context) ShouldBindJSON(obj any) error {
+	return c.ShouldBindWith(obj, binding.JSON)
+}
+
+// ShouldBindXML 是c.ShouldBindWith(obj, binding.XML)的简写
+func (c *Context) ShouldBindXML(obj any) error {
+	return c.ShouldBindWith(obj, binding.XML)
+}
+
+// ShouldBindQuery 是c.ShouldBindWith(obj, binding.Query)的简写
+func (c *Context) ShouldBindQuery(obj any) error {
+	return c.ShouldBindWith(obj, binding.Query)
+}
+
+// ShouldBindHeader 是c.ShouldBindWith(obj, binding.Header)的简写
+func (c *Context) ShouldBindHeader(obj any) error {
+	return c.ShouldBindWith(obj, binding.Header)
+}
+
+// ShouldBindUri 使用binding.Uri绑定路由参数
+func (c *Context) ShouldBindUri(obj any) error {
+	m := make(map[string][]string, len(c.Params))
+	for _, v := range c.Params {
+		m[v.Key] = []string{v.Value}
+	}
+	return binding.Uri.BindUri(m, obj)
+}
+
+// ShouldBindWith 使用指定的binding，表单类的binding按照Engine.MaxMultipartMemory解析multipart表单
+func (c *Context) ShouldBindWith(obj any, b binding.Binding) error {
+	if (b == binding.Form || b == binding.FormMultipart) && c.R.MultipartForm == nil {
+		err := c.R.ParseMultipartForm(c.engine.MaxMultipartMemory)
+		if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return err
+		}
+	}
+	return b.Bind(c.R, obj)
+}
+
+// GetHeader 返回请求头
+func (c *Context) GetHeader(key string) string {
+	return c.R.Header.Get(key)
+}
+
+// Header 设置响应头，value为空时删除
+func (c *Context) Header(key, value string) {
+	if value == "" {
+		c.W.Header().Del(key)
+		return
+	}
+	c.W.Header().Set(key, value)
+}
+
+// ContentType 返回请求的Content-Type，不包括charset等参数
+func (c *Context) ContentType() string {
+	return filterFlags(c.GetHeader("Content-Type"))
+}
+
+// Cookie 返回name对应的cookie，已经过url解码
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.R.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	val, _ := url.QueryUnescape(cookie.Value)
+	return val, nil
+}
+
+// SetSameSite 设置之后SetCookie使用的SameSite
+func (c *Context) SetSameSite(samesite http.SameSite) {
+	c.sameSite = samesite
+}
+
+// SetCookie 在响应中设置cookie，value会经过url编码
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.W, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// ClientIP 返回客户端的IP
+// 设置了Engine.TrustedPlatform时优先读取对应的请求头，
+// 否则只有RemoteIP为可信代理时，才会从Engine.RemoteIPHeaders中解析
+func (c *Context) ClientIP() string {
+	if c.engine.TrustedPlatform != "" {
+		if addr := c.GetHeader(c.engine.TrustedPlatform); addr != "" {
+			return addr
+		}
+	}
+
+	remoteIP := net.ParseIP(c.RemoteIP())
+	if remoteIP == nil {
+		return ""
+	}
+
+	if c.engine.ForwardedByClientIP && c.engine.isTrustedProxy(remoteIP) {
+		for _, headerName := range c.engine.RemoteIPHeaders {
+			if ip, valid := c.engine.validateHeader(c.GetHeader(headerName)); valid {
+				return ip
+			}
+		}
+	}
+	return remoteIP.String()
+}
+
+// RemoteIP 返回直接连接的对端IP，即Request.RemoteAddr中的IP
+func (c *Context) RemoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.R.RemoteAddr))
+	if err != nil {
+		return ""
+	}
+	return ip
+}
+
 func (c *Context) Status(code int) {
 	if code > 0 {
 		c.W.WriteHeader(code)
@@ -113,18 +524,183 @@ func (c *Context) Status(code int) {
 }
 
 func (c *Context) String(code int, format string, values ...any) {
-	c.Render(code, render.String{format, values})
+	c.Render(code, render.String{Format: format, Data: values})
 }
 
 func (c *Context) JSON(code int, obj any) {
 	c.Render(code, render.JSON{Data: obj})
 }
 
+// IndentedJSON 输出带缩进的JSON，比JSON消耗更多资源，建议只在调试时使用
+func (c *Context) IndentedJSON(code int, obj any) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+// SecureJSON 输出JSON，obj为数组时加上Engine.SecureJSONPrefix设置的前缀
+func (c *Context) SecureJSON(code int, obj any) {
+	c.Render(code, render.SecureJSON{Prefix: c.engine.secureJSONPrefix, Data: obj})
+}
+
+// JSONP 使用query中的callback输出JSONP，没有callback时输出JSON
+func (c *Context) JSONP(code int, obj any) {
+	callback := c.DefaultQuery("callback", "")
+	if callback == "" {
+		c.Render(code, render.JSON{Data: obj})
+		return
+	}
+	if !render.ValidCallback(callback) {
+		c.AbortWithError(http.StatusBadRequest, render.ErrInvalidCallback).SetType(ErrorTypePublic)
+		return
+	}
+	c.Render(code, render.JsonpJSON{Callback: callback, Data: obj})
+}
+
+// AsciiJSON 输出只包含ASCII字符的JSON，非ASCII字符会被转义
+func (c *Context) AsciiJSON(code int, obj any) {
+	c.Render(code, render.AsciiJSON{Data: obj})
+}
+
+// PureJSON 输出不转义HTML字符的JSON
+func (c *Context) PureJSON(code int, obj any) {
+	c.Render(code, render.PureJSON{Data: obj})
+}
+
+func (c *Context) XML(code int, obj any) {
+	c.Render(code, render.XML{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj any) {
+	c.Render(code, render.YAML{Data: obj})
+}
+
+func (c *Context) TOML(code int, obj any) {
+	c.Render(code, render.TOML{Data: obj})
+}
+
+// ProtoBuf 输出protobuf，obj必须是proto.Message
+func (c *Context) ProtoBuf(code int, obj any) {
+	c.Render(code, render.ProtoBuf{Data: obj})
+}
+
 func (c *Context) HTML(code int, name string, obj any) {
+	if c.engine.HTMLRender == nil {
+		debugPrint("[WARNING] HTMLRender is nil, call LoadHTMLGlob or LoadHTMLFiles before rendering %q", name)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
 	instance := c.engine.HTMLRender.Instance(name, obj)
 	c.Render(code, instance)
 }
 
+// Data 输出原始的字节
+func (c *Context) Data(code int, contentType string, data []byte) {
+	c.Render(code, render.Data{
+		ContentType: contentType,
+		Data:        data,
+	})
+}
+
+// DataFromReader 输出reader中的内容，contentLength小于0时不设置Content-Length
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	c.Render(code, render.Reader{
+		Headers:       extraHeaders,
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Reader:        reader,
+	})
+}
+
+// File 输出文件，支持Range和If-Modified-Since等请求头
+func (c *Context) File(file string) {
+	http.ServeFile(c.W, c.R, file)
+}
+
+// FileAttachment 以附件的形式输出文件，浏览器会使用filename作为下载的文件名
+// 非ASCII的文件名按照RFC 5987编码到filename*中，同时提供非ASCII字符替换为"_"的filename
+func (c *Context) FileAttachment(file, filename string) {
+	if isASCII(filename) {
+		c.W.Header().Set("Content-Disposition", `attachment; filename="`+escapeQuotes(filename)+`"`)
+	} else {
+		c.W.Header().Set("Content-Disposition", `attachment; filename="`+escapeQuotes(asciiFallback(filename))+
+			`"; filename*=UTF-8''`+encodeRFC5987(filename))
+	}
+	http.ServeFile(c.W, c.R, file)
+}
+
+// Stream 循环调用step输出流式响应，每次调用后flush
+// step返回false或者客户端断开连接时结束，客户端断开时返回true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	w := c.W
+	clientGone := c.R.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(w)
+			w.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// SSEvent 输出一条Server-Sent Events消息，可以多次调用
+func (c *Context) SSEvent(name string, data any) {
+	c.RenderSSEvent(render.SSEvent{Event: name, Data: data})
+}
+
+// RenderSSEvent 输出一条完整的SSEvent，和Render不同，响应已经写出时仍然会继续输出
+func (c *Context) RenderSSEvent(event render.SSEvent) {
+	if err := event.Render(c.W); err != nil {
+		_ = c.Error(err).SetType(ErrorTypeRender)
+		c.Logger().Error("render error", slog.Any("error", err))
+	}
+}
+
+// LastEventID 返回客户端重连时携带的Last-Event-ID，用于从断点恢复事件流
+func (c *Context) LastEventID() string {
+	return c.GetHeader("Last-Event-ID")
+}
+
+// SSEStream 设置事件流的响应头并立即写出，然后按照Stream的方式循环调用step
+// 设置了X-Accel-Buffering，避免nginx等反向代理缓冲事件
+func (c *Context) SSEStream(step func(w io.Writer) bool) bool {
+	header := c.W.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.W.WriteHeaderNow()
+	c.W.Flush()
+	return c.Stream(step)
+}
+
+// IsWebsocket 判断当前请求是否是WebSocket握手请求
+func (c *Context) IsWebsocket() bool {
+	return websocket.IsWebSocketUpgrade(c.R)
+}
+
+// Upgrade 使用Engine.Upgrader将当前请求升级为WebSocket连接
+// 应在handler中调用，此时中间件（如鉴权）已经执行过，升级之后不能再使用c.W
+// 握手失败时已经写出错误响应并终止请求
+func (c *Context) Upgrade() (*websocket.Conn, error) {
+	c.Status(http.StatusSwitchingProtocols)
+	// 中间件设置的响应头（如Set-Cookie）会一起写入101响应
+	conn, err := c.engine.Upgrader.Upgrade(c.W, c.R, c.W.Header())
+	if err != nil {
+		if !c.W.Written() {
+			c.Status(http.StatusInternalServerError)
+		}
+		_ = c.Error(err)
+		c.Abort()
+		return nil, err
+	}
+	c.Abort()
+	return conn, nil
+}
+
 func (c *Context) Redirect(code int, location string) {
 	c.Render(-1, render.Redirect{
 		Code:     code,
@@ -134,35 +710,95 @@ func (c *Context) Redirect(code int, location string) {
 }
 
 func (c *Context) Render(code int, r render.Render) {
+	// 请求体超出限制时不再输出handler的响应
+	c.writeBodyTooLarge()
 	// 暂未考虑并发
-	if c.statusCode != 0 {
-		// TODO 处理警告
-		log.Println("[warn] render already, skip")
+	if c.W.Written() {
+		c.Logger().Warn("render already, skip", slog.Int("status", c.W.Status()))
 		return
 	}
-	c.statusCode = code
 	c.Status(code)
 	if err := r.Render(c.W); err != nil {
-		// TODO handle error
-		//_ = c.Error(err)
-		log.Println(err)
+		_ = c.Error(err).SetType(ErrorTypeRender)
+		c.Logger().Error("render error", slog.Any("error", err))
 	}
 	c.Abort()
 }
 
+// Set 在当前请求中保存一个值，可以在多个goroutine中并发调用
 func (c *Context) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.Keys == nil {
 		c.Keys = make(map[string]any)
 	}
 	c.Keys[key] = value
 }
 
-func (c *Context) Get(key string) (any, error) {
-	v, ok := c.Keys[key]
-	if ok {
-		return v, nil
+// Get 返回Set保存的值，key不存在时ok为false
+func (c *Context) Get(key string) (value any, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok = c.Keys[key]
+	return
+}
+
+// MustGet 返回Set保存的值，key不存在时panic
+func (c *Context) MustGet(key string) any {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	panic("key \"" + key + "\" does not exist")
+}
+
+// GetAs 返回Set保存的T类型的值，key不存在或者类型不符时ok为false
+func GetAs[T any](c *Context, key string) (value T, ok bool) {
+	if val, exists := c.Get(key); exists {
+		value, ok = val.(T)
 	}
-	return nil, errors.New("key not found in context keys : " + key)
+	return
+}
+
+// GetString 返回key对应的string，不存在时返回空字符串
+func (c *Context) GetString(key string) (s string) {
+	s, _ = GetAs[string](c, key)
+	return
+}
+
+// GetBool 返回key对应的bool
+func (c *Context) GetBool(key string) (b bool) {
+	b, _ = GetAs[bool](c, key)
+	return
+}
+
+// GetInt 返回key对应的int
+func (c *Context) GetInt(key string) (i int) {
+	i, _ = GetAs[int](c, key)
+	return
+}
+
+// GetInt64 返回key对应的int64
+func (c *Context) GetInt64(key string) (i64 int64) {
+	i64, _ = GetAs[int64](c, key)
+	return
+}
+
+// GetDuration 返回key对应的time.Duration
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	d, _ = GetAs[time.Duration](c, key)
+	return
+}
+
+// GetTime 返回key对应的time.Time
+func (c *Context) GetTime(key string) (t time.Time) {
+	t, _ = GetAs[time.Time](c, key)
+	return
+}
+
+// GetStringSlice 返回key对应的[]string
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	ss, _ = GetAs[[]string](c, key)
+	return
 }
 
 func (c *Context) Next() {
@@ -177,6 +813,117 @@ func (c *Context) Abort() {
 	c.index = abortIndex
 }
 
+// AbortWithError 设置状态码，记录err并停止执行后续的handlers
+// 响应头不会立即写出，之后的ErrorHandler等中间件仍然可以输出响应体
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.Status(code)
+	c.Abort()
+	return c.Error(err)
+}
+
+// Error 将err记录到c.Errors中，通常在中间件中统一处理
+// err为nil时panic
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("err is nil")
+	}
+
+	var parsedError *Error
+	ok := errors.As(err, &parsedError)
+	if !ok {
+		parsedError = &Error{
+			Err:  err,
+			Type: ErrorTypePrivate,
+		}
+	}
+
+	c.Errors = append(c.Errors, parsedError)
+	return parsedError
+}
+
+// AbortWithStatus 写出状态码并停止执行后续的handlers
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.W.WriteHeaderNow()
+	c.Abort()
+}
+
 func (c *Context) IsAborted() bool {
 	return c.index >= abortIndex
 }
+
+// Copy 返回当前Context的副本，用于在handler返回后仍需使用Context的goroutine
+// 副本不能写入响应，也不会执行后续的handlers
+func (c *Context) Copy() *Context {
+	cp := Context{
+		writermem: c.writermem,
+		R:         c.R,
+		engine:    c.engine,
+		fullPath:  c.fullPath,
+	}
+	cp.writermem.ResponseWriter = nil
+	cp.W = &cp.writermem
+	cp.index = abortIndex
+	cp.handlers = nil
+	cp.Keys = c.copyKeys()
+	cp.Params = make(Params, len(c.Params))
+	copy(cp.Params, c.Params)
+	return &cp
+}
+
+// copyKeys 在读锁下复制Keys，可以在其他goroutine仍在Set时安全使用
+func (c *Context) copyKeys() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make(map[string]any, len(c.Keys))
+	for k, v := range c.Keys {
+		keys[k] = v
+	}
+	return keys
+}
+
+func (c *Context) hasRequestContext() bool {
+	return c.engine != nil && c.engine.ContextWithFallback && c.R != nil
+}
+
+// Deadline 在Engine.ContextWithFallback为true时返回请求context的截止时间
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if !c.hasRequestContext() {
+		return
+	}
+	return c.R.Context().Deadline()
+}
+
+// Done 在Engine.ContextWithFallback为true时返回请求context的Done，
+// 客户端断开连接或服务关闭时会被关闭
+func (c *Context) Done() <-chan struct{} {
+	if !c.hasRequestContext() {
+		return nil
+	}
+	return c.R.Context().Done()
+}
+
+// Err 在Engine.ContextWithFallback为true时返回请求context的Err
+func (c *Context) Err() error {
+	if !c.hasRequestContext() {
+		return nil
+	}
+	return c.R.Context().Err()
+}
+
+// Value 优先从Keys中查找string类型的key，
+// 找不到且Engine.ContextWithFallback为true时再从请求context中查找
+func (c *Context) Value(key any) any {
+	if key == ContextKey {
+		return c
+	}
+	if keyAsString, ok := key.(string); ok {
+		if val, exists := c.Get(keyAsString); exists {
+			return val
+		}
+	}
+	if !c.hasRequestContext() {
+		return nil
+	}
+	return c.R.Context().Value(key)
+}
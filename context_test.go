@@ -0,0 +1,551 @@
+package rough
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cainmusic/rough/render"
+	"github.com/cainmusic/rough/websocket"
+)
+
+func TestContextLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	en := New()
+	en.Logger = slog.New(slog.NewTextHandler(buf, nil))
+	en.GET("/user/:id", func(c *Context) {
+		c.Logger().Info("hello")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	en.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	for _, want := range []string{"msg=hello", "request_id=abc", "method=GET", "route=/user/:id"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q does not contain %q", out, want)
+		}
+	}
+}
+
+type ctxKey struct{}
+
+func TestContextAsContext(t *testing.T) {
+	en := New()
+	en.ContextWithFallback = true
+	en.GET("/", func(c *Context) {
+		c.Set("user", "rough")
+		if c.Value("user") != "rough" {
+			t.Errorf("Value(user) = %v", c.Value("user"))
+		}
+		if c.Value(ctxKey{}) != "from request" {
+			t.Errorf("Value(ctxKey) = %v", c.Value(ctxKey{}))
+		}
+		if c.Value(ContextKey) != c {
+			t.Error("Value(ContextKey) should return the Context")
+		}
+		if c.Done() == nil {
+			t.Error("Done() should delegate to the request context")
+		}
+
+		cp := c.Copy()
+		c.Set("user", "changed")
+		if v, _ := cp.Get("user"); v != "rough" {
+			t.Errorf("copied Keys = %v", v)
+		}
+		if !cp.IsAborted() {
+			t.Error("copy should not run handlers")
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "from request"))
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	en.ServeHTTP(httptest.NewRecorder(), req)
+
+	c := &Context{engine: New()}
+	if c.Done() != nil || c.Err() != nil || c.Value(ctxKey{}) != nil {
+		t.Error("without ContextWithFallback the request context should not be used")
+	}
+}
+
+func TestContextKeys(t *testing.T) {
+	c := &Context{}
+	now := time.Now()
+	c.Set("string", "rough")
+	c.Set("int", 1)
+	c.Set("int64", int64(2))
+	c.Set("bool", true)
+	c.Set("duration", time.Second)
+	c.Set("time", now)
+	c.Set("slice", []string{"a", "b"})
+
+	if c.GetString("string") != "rough" || c.GetInt("int") != 1 || c.GetInt64("int64") != 2 ||
+		!c.GetBool("bool") || c.GetDuration("duration") != time.Second || !c.GetTime("time").Equal(now) ||
+		len(c.GetStringSlice("slice")) != 2 {
+		t.Errorf("typed getters returned wrong values: %v", c.Keys)
+	}
+	if c.GetString("int") != "" {
+		t.Error("GetString on int should return zero value")
+	}
+	if v, ok := GetAs[int64](c, "int64"); !ok || v != 2 {
+		t.Errorf("GetAs[int64] = %v, %v", v, ok)
+	}
+	if _, ok := GetAs[int](c, "missing"); ok {
+		t.Error("GetAs on missing key should return false")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustGet on missing key should panic")
+		}
+	}()
+	c.MustGet("missing")
+}
+
+func TestContextKeysConcurrent(t *testing.T) {
+	c := &Context{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Set("key", i)
+			c.Get("key")
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestContextBind(t *testing.T) {
+	type user struct {
+		ID   int    `uri:"id"`
+		Name string `form:"name" json:"name"`
+	}
+	en := New()
+	en.POST("/user/:id", func(c *Context) {
+		var u user
+		if err := c.ShouldBindUri(&u); err != nil {
+			t.Error(err)
+		}
+		if err := c.Bind(&u); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "%d %s", u.ID, u.Name)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/user/1", strings.NewReader(`{"name":"rough"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	en.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "1 rough" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/user/1", strings.NewReader(`{"name":`))
+	req.Header.Set("Content-Type", "application/json")
+	en.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestContextFormFile(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	_, _ = fw.Write([]byte("hello"))
+	mw.Close()
+
+	dir := t.TempDir()
+	en := New()
+	en.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if form, err := c.MultipartForm(); err != nil || len(form.File["file"]) != 1 {
+			t.Errorf("MultipartForm() = %v, %v", form, err)
+		}
+		if err := c.SaveUploadedFile(fh, dir+"/../a.txt"); err == nil {
+			t.Error("expected error for path traversal")
+		}
+		if err := c.SaveUploadedFile(fh, filepath.Join(dir, "sub", fh.Filename)); err != nil {
+			t.Error(err)
+		}
+		for _, name := range []string{"../a.txt", "sub/../../a.txt", "../../etc/x", "/etc/x", ""} {
+			if err := c.SaveUploadedFileTo(fh, dir, name); err == nil {
+				t.Errorf("expected error for %q", name)
+			}
+		}
+		if err := c.SaveUploadedFileTo(fh, dir, filepath.Join("to", fh.Filename)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	en.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, sub := range []string{"sub", "to"} {
+		if data, err := os.ReadFile(filepath.Join(dir, sub, "a.txt")); err != nil || string(data) != "hello" {
+			t.Errorf("saved file in %s = %q, %v", sub, data, err)
+		}
+	}
+}
+
+func TestMaxRequestBodySize(t *testing.T) {
+	en := New()
+	en.MaxRequestBodySize = 8
+	en.POST("/", func(c *Context) {
+		var obj map[string]any
+		if err := c.BindJSON(&obj); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"rough"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+
+	// Content-Length未知时在读取时才会发现超出
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"rough"}`))
+	req.ContentLength = -1
+	en.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestMaxRequestBodySizeUnknownLength(t *testing.T) {
+	en := New()
+	en.MaxRequestBodySize = 16
+	en.POST("/form", func(c *Context) {
+		c.String(http.StatusOK, "name="+c.PostForm("name"))
+	})
+	en.POST("/file", func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	en.POST("/multipart", func(c *Context) {
+		_, _ = c.MultipartForm()
+		c.String(http.StatusOK, "ok")
+	})
+	en.POST("/raw", func(c *Context) {
+		b, _ := io.ReadAll(c.R.Body)
+		c.String(http.StatusOK, string(b))
+	})
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	_, _ = fw.Write([]byte(strings.Repeat("x", 64)))
+	mw.Close()
+
+	cases := []struct {
+		path, contentType, body string
+	}{
+		{"/form", "application/x-www-form-urlencoded", "name=" + strings.Repeat("x", 64)},
+		{"/file", mw.FormDataContentType(), body.String()},
+		{"/multipart", mw.FormDataContentType(), body.String()},
+		{"/raw", "text/plain", strings.Repeat("x", 64)},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != "413 request entity too large" {
+			t.Errorf("%s: got %d %q, want 413", tc.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBindUsesMaxMultipartMemory(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	_, _ = fw.Write([]byte(strings.Repeat("x", 64)))
+	mw.Close()
+
+	en := New()
+	en.MaxMultipartMemory = 1
+	en.POST("/", func(c *Context) {
+		var obj struct {
+			File *multipart.FileHeader `form:"file" binding:"required"`
+		}
+		if err := c.ShouldBind(&obj); err != nil {
+			t.Fatal(err)
+		}
+		f, err := obj.File.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		// 超出MaxMultipartMemory的文件会写入临时文件
+		if _, ok := f.(*os.File); !ok {
+			t.Errorf("file kept in memory (%T), MaxMultipartMemory ignored", f)
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	en.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestContextQueryAndFormMap(t *testing.T) {
+	en := New()
+	en.POST("/", func(c *Context) {
+		if m := c.QueryMap("filter"); len(m) != 2 || m["status"] != "open" || m["owner"] != "me" {
+			t.Errorf("QueryMap = %v", m)
+		}
+		if _, ok := c.GetQueryMap("missing"); ok {
+			t.Error("GetQueryMap(missing) should return false")
+		}
+		if m := c.PostFormMap("names"); m["first"] != "rough" {
+			t.Errorf("PostFormMap = %v", m)
+		}
+		if v := c.DefaultQuery("page", "1"); v != "1" {
+			t.Errorf("DefaultQuery = %q", v)
+		}
+		if v := c.DefaultPostForm("names[first]", "x"); v != "rough" {
+			t.Errorf("DefaultPostForm = %q", v)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/?filter[status]=open&filter[owner]=me",
+		strings.NewReader("names[first]=rough&names[last]=go"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	en.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestContextDataAndFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("hello file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	en := New()
+	en.GET("/data", func(c *Context) {
+		c.Data(http.StatusOK, "application/octet-stream", []byte{1, 2, 3})
+	})
+	en.GET("/reader", func(c *Context) {
+		c.DataFromReader(http.StatusOK, 5, "text/plain", strings.NewReader("hello"),
+			map[string]string{"X-Extra": "1"})
+	})
+	en.GET("/file", func(c *Context) {
+		c.File(file)
+	})
+	en.GET("/attachment", func(c *Context) {
+		c.FileAttachment(file, "报告.txt")
+	})
+	en.GET("/attachment2", func(c *Context) {
+		c.FileAttachment(file, "报告 a=b@e:1.pdf")
+	})
+	en.GET("/attachment3", func(c *Context) {
+		c.FileAttachment(file, `a "b".txt`)
+	})
+
+	cases := []struct {
+		path, header, value, body string
+	}{
+		{"/data", "Content-Type", "application/octet-stream", "\x01\x02\x03"},
+		{"/reader", "Content-Length", "5", "hello"},
+		{"/reader", "X-Extra", "1", "hello"},
+		{"/file", "Content-Type", "text/plain; charset=utf-8", "hello file"},
+		{"/attachment", "Content-Disposition", `attachment; filename="__.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt`, "hello file"},
+		{"/attachment2", "Content-Disposition", `attachment; filename="__ a=b@e:1.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%20a%3Db%40e%3A1.pdf`, "hello file"},
+		{"/attachment3", "Content-Disposition", `attachment; filename="a \"b\".txt"`, "hello file"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if got := w.Header().Get(tc.header); got != tc.value {
+			t.Errorf("%s: %s = %q, want %q", tc.path, tc.header, got, tc.value)
+		}
+		if w.Body.String() != tc.body {
+			t.Errorf("%s: body = %q, want %q", tc.path, w.Body.String(), tc.body)
+		}
+	}
+}
+
+func TestContextStream(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		i := 0
+		gone := c.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "%d;", i)
+			return i < 3
+		})
+		if gone {
+			t.Error("client should not be gone")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "1;2;3;" || !w.Flushed {
+		t.Errorf("body = %q, flushed = %v", w.Body.String(), w.Flushed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	en.GET("/gone", func(c *Context) {
+		if !c.Stream(func(io.Writer) bool { return true }) {
+			t.Error("Stream should stop when the client is gone")
+		}
+	})
+	en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/gone", nil).WithContext(ctx))
+}
+
+func TestContextSSEStream(t *testing.T) {
+	en := New()
+	en.GET("/events", func(c *Context) {
+		id, _ := strconv.Atoi(c.LastEventID())
+		c.SSEStream(func(w io.Writer) bool {
+			id++
+			c.RenderSSEvent(render.SSEvent{Id: strconv.Itoa(id), Event: "progress", Data: id})
+			return id < 3
+		})
+		c.SSEvent("done", "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, req)
+
+	want := "id: 2\nevent: progress\ndata: 2\n\n" +
+		"id: 3\nevent: progress\ndata: 3\n\n" +
+		"event: done\ndata: ok\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("X-Accel-Buffering") != "no" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+}
+
+func TestContextUpgrade(t *testing.T) {
+	en := New()
+	en.Use(func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.SetCookie("session", "abc", 0, "/", "", false, true)
+	})
+	en.GET("/ws", func(c *Context) {
+		if !c.IsWebsocket() {
+			c.String(http.StatusBadRequest, "not websocket")
+			return
+		}
+		conn, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, append([]byte("echo: "), p...)); err != nil {
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewServer(en)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	_, resp, err := websocket.Dial(url, nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthorized upgrade: err = %v", err)
+	}
+
+	conn, resp, err := websocket.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if cookie := resp.Header.Get("Set-Cookie"); !strings.HasPrefix(cookie, "session=abc") {
+		t.Errorf("Set-Cookie from middleware = %q", cookie)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "echo: hi" {
+		t.Errorf("got %q, %v", p, err)
+	}
+}
+
+func TestContextJSONP(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		c.JSONP(http.StatusOK, map[string]int{"a": 1})
+	})
+
+	cases := []struct {
+		query, contentType, body string
+		code                     int
+	}{
+		{"", "application/json; charset=utf-8", `{"a":1}`, http.StatusOK},
+		{"?callback=cb", "application/javascript; charset=utf-8", `/**/cb({"a":1});`, http.StatusOK},
+		{"?callback=alert(1)//", "", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tc.query, nil))
+		if w.Code != tc.code || w.Body.String() != tc.body || w.Header().Get("Content-Type") != tc.contentType {
+			t.Errorf("%q: got %d %q %q", tc.query, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestContextSecureJSON(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		c.SecureJSON(http.StatusOK, []int{1, 2})
+	})
+
+	for _, prefix := range []string{"while(1);", ")]}',\n"} {
+		if prefix != "while(1);" {
+			en.SecureJSONPrefix(prefix)
+		}
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if want := prefix + "[1,2]"; w.Body.String() != want {
+			t.Errorf("body = %q, want %q", w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package rough
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// DebugPrintRouteFunc 用于在DebugMode下输出注册的路由，为nil时使用默认格式
+var DebugPrintRouteFunc func(httpMethod, absolutePath, handlerName string, nuHandlers int)
+
+// DebugPrintFunc 用于在DebugMode下输出调试信息，为nil时输出到DefaultWriter
+var DebugPrintFunc func(format string, values ...any)
+
+// IsDebugging 返回当前是否为DebugMode
+func IsDebugging() bool {
+	return roughMode.Load() == debugCode
+}
+
+func debugPrintRoute(httpMethod, absolutePath string, handlers []HandleFunc) {
+	if IsDebugging() {
+		nuHandlers := len(handlers)
+		handlerName := nameOfFunction(handlers[nuHandlers-1])
+		if DebugPrintRouteFunc == nil {
+			debugPrint("%-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
+		} else {
+			DebugPrintRouteFunc(httpMethod, absolutePath, handlerName, nuHandlers)
+		}
+	}
+}
+
+func debugPrint(format string, values ...any) {
+	if !IsDebugging() {
+		return
+	}
+
+	if DebugPrintFunc != nil {
+		DebugPrintFunc(format, values...)
+		return
+	}
+
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	fmt.Fprintf(DefaultWriter, "[ROUGH-debug] "+format, values...)
+}
+
+func debugPrintWARNINGDefault() {
+	debugPrint("[WARNING] Creating an Engine instance with the Logger and Recovery middleware already attached.\n\n")
+}
+
+func debugPrintWARNINGNew() {
+	debugPrint(`[WARNING] Running in "debug" mode. Switch to "release" mode in production.
+ - using env:	export ROUGH_MODE=release
+ - using code:	rough.SetMode(rough.ReleaseMode)
+
+`)
+}
+
+func debugPrintLoadTemplate(templ *template.Template) {
+	if IsDebugging() {
+		var buf strings.Builder
+		for _, t := range templ.Templates() {
+			if t.Name() != "" {
+				buf.WriteString("\t- ")
+				buf.WriteString(t.Name())
+				buf.WriteString("\n")
+			}
+		}
+		debugPrint("Loaded HTML Templates (%d): \n%s\n", len(templ.Templates()), buf.String())
+	}
+}
+
+func debugPrintWARNINGSetHTMLTemplate() {
+	debugPrint(`[WARNING] Since SetHTMLTemplate() is NOT thread-safe. It should only be called
+at initialization. ie. before any route is registered or the router is listening in a socket:
+
+	router := rough.Default()
+	router.SetHTMLTemplate(template) // << good place
+
+`)
+}
@@ -0,0 +1,111 @@
+package rough
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cainmusic/rough/render"
+)
+
+func TestDebugPrintRoute(t *testing.T) {
+	SetMode(DebugMode)
+	defer SetMode(TestMode)
+
+	out := DefaultWriter
+	DefaultWriter = new(bytes.Buffer)
+	defer func() { DefaultWriter = out }()
+
+	var method, path string
+	var n int
+	DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
+		method, path, n = httpMethod, absolutePath, nuHandlers
+	}
+	defer func() { DebugPrintRouteFunc = nil }()
+
+	en := New()
+	en.Use(func(c *Context) {})
+	en.GET("/debug/:id", func(c *Context) {})
+	if method != http.MethodGet || path != "/debug/:id" || n != 2 {
+		t.Errorf("got %s %s %d", method, path, n)
+	}
+}
+
+func TestDebugPrintReleaseMode(t *testing.T) {
+	SetMode(ReleaseMode)
+	defer SetMode(TestMode)
+
+	buf := new(bytes.Buffer)
+	out := DefaultWriter
+	DefaultWriter = buf
+	defer func() { DefaultWriter = out }()
+
+	en := New()
+	en.GET("/", func(c *Context) {})
+	if buf.Len() != 0 {
+		t.Errorf("release mode printed %q", buf.String())
+	}
+}
+
+func TestLoadHTMLGlobDebugReload(t *testing.T) {
+	SetMode(DebugMode)
+	defer SetMode(TestMode)
+
+	buf := new(bytes.Buffer)
+	out := DefaultWriter
+	DefaultWriter = buf
+	defer func() { DefaultWriter = out }()
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(page, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	en := New()
+	en.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	en.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "index.html", nil)
+	})
+	if !strings.Contains(buf.String(), "- index.html") {
+		t.Errorf("loaded templates not printed: %q", buf.String())
+	}
+
+	for _, want := range []string{"v1", "v2"} {
+		if err := os.WriteFile(page, []byte(want), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Body.String() != want {
+			t.Errorf("body = %q, want %q", w.Body.String(), want)
+		}
+	}
+}
+
+func TestSetHTMLRenderFollowsMode(t *testing.T) {
+	out := DefaultWriter
+	DefaultWriter = new(bytes.Buffer)
+	defer func() { DefaultWriter = out }()
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(page, []byte("page"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{DebugMode, ReleaseMode} {
+		SetMode(mode)
+		multi := render.NewHTMLMulti()
+		multi.AddFromFiles("page", page)
+		New().SetHTMLRender(multi)
+		if multi.Debug != (mode == DebugMode) {
+			t.Errorf("%s: Debug = %v", mode, multi.Debug)
+		}
+	}
+	SetMode(TestMode)
+}
@@ -0,0 +1,185 @@
+package rough
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/cainmusic/rough/binding"
+)
+
+// ErrorType 是错误类型的标志位
+type ErrorType uint64
+
+const (
+	// ErrorTypeBind 用于请求绑定失败
+	ErrorTypeBind ErrorType = 1 << 63
+	// ErrorTypeRender 用于渲染失败
+	ErrorTypeRender ErrorType = 1 << 62
+	// ErrorTypePrivate 表示不应返回给客户端的错误
+	ErrorTypePrivate ErrorType = 1 << 0
+	// ErrorTypePublic 表示可以返回给客户端的错误
+	ErrorTypePublic ErrorType = 1 << 1
+	// ErrorTypeAny 匹配所有类型
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// Error 是请求处理过程中收集到的错误
+type Error struct {
+	Err  error
+	Type ErrorType
+	Meta any
+}
+
+type errorMsgs []*Error
+
+var _ error = (*Error)(nil)
+
+// SetType 设置错误类型
+func (msg *Error) SetType(flags ErrorType) *Error {
+	msg.Type = flags
+	return msg
+}
+
+// SetMeta 设置错误的附加信息
+func (msg *Error) SetMeta(data any) *Error {
+	msg.Meta = data
+	return msg
+}
+
+// JSON 返回用于输出JSON的数据
+func (msg *Error) JSON() any {
+	jsonData := H{}
+	if msg.Meta != nil {
+		value := reflect.ValueOf(msg.Meta)
+		switch value.Kind() {
+		case reflect.Struct:
+			return msg.Meta
+		case reflect.Map:
+			for _, key := range value.MapKeys() {
+				jsonData[key.String()] = value.MapIndex(key).Interface()
+			}
+		default:
+			jsonData["meta"] = msg.Meta
+		}
+	}
+	if _, ok := jsonData["error"]; !ok {
+		jsonData["error"] = msg.Error()
+	}
+	return jsonData
+}
+
+func (msg *Error) Error() string {
+	return msg.Err.Error()
+}
+
+// IsType 判断错误是否为flags中的类型
+func (msg *Error) IsType(flags ErrorType) bool {
+	return (msg.Type & flags) > 0
+}
+
+// Unwrap 返回原始错误，供errors.Is和errors.As使用
+func (msg *Error) Unwrap() error {
+	return msg.Err
+}
+
+// ByType 返回指定类型的错误
+func (a errorMsgs) ByType(typ ErrorType) errorMsgs {
+	if len(a) == 0 {
+		return nil
+	}
+	if typ == ErrorTypeAny {
+		return a
+	}
+	var result errorMsgs
+	for _, msg := range a {
+		if msg.IsType(typ) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// Last 返回最后一个错误，没有错误时返回nil
+func (a errorMsgs) Last() *Error {
+	if length := len(a); length > 0 {
+		return a[length-1]
+	}
+	return nil
+}
+
+// Errors 返回所有错误的信息
+func (a errorMsgs) Errors() []string {
+	if len(a) == 0 {
+		return nil
+	}
+	errorStrings := make([]string, len(a))
+	for i, err := range a {
+		errorStrings[i] = err.Error()
+	}
+	return errorStrings
+}
+
+// JSON 返回用于输出JSON的数据
+func (a errorMsgs) JSON() any {
+	switch length := len(a); length {
+	case 0:
+		return nil
+	case 1:
+		return a.Last().JSON()
+	default:
+		jsonData := make([]any, length)
+		for i, err := range a {
+			jsonData[i] = err.JSON()
+		}
+		return jsonData
+	}
+}
+
+func (a errorMsgs) String() string {
+	if len(a) == 0 {
+		return ""
+	}
+	var buffer strings.Builder
+	for i, msg := range a {
+		fmt.Fprintf(&buffer, "Error #%02d: %s\n", i+1, msg.Err)
+		if msg.Meta != nil {
+			fmt.Fprintf(&buffer, "     Meta: %v\n", msg.Meta)
+		}
+	}
+	return buffer.String()
+}
+
+// ErrorHandler 返回一个中间件，在后续handlers执行完且没有写出响应时，
+// 将收集到的错误按照Accept输出为JSON或者纯文本，默认为纯文本
+// 只有ErrorTypePublic的错误会输出具体信息，其余只输出状态码对应的描述
+func ErrorHandler() HandleFunc {
+	return func(c *Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.W.Written() {
+			return
+		}
+
+		code := c.W.Status()
+		if code < http.StatusBadRequest {
+			code = http.StatusInternalServerError
+		}
+
+		public := c.Errors.ByType(ErrorTypePublic)
+		if c.NegotiateFormat(binding.MIMEPlain, binding.MIMEJSON) == binding.MIMEJSON {
+			if len(public) == 0 {
+				c.JSON(code, H{"error": http.StatusText(code)})
+				return
+			}
+			c.JSON(code, H{"errors": public.JSON()})
+			return
+		}
+		if len(public) == 0 {
+			c.String(code, http.StatusText(code))
+			return
+		}
+		c.String(code, strings.Join(public.Errors(), "\n"))
+	}
+}
@@ -0,0 +1,54 @@
+package rough
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextError(t *testing.T) {
+	c := &Context{}
+	err := c.Error(errors.New("first")).SetType(ErrorTypePublic).SetMeta("some data")
+	c.Error(errors.New("second"))
+
+	if len(c.Errors) != 2 || c.Errors.Last().Error() != "second" {
+		t.Fatalf("errors = %v", c.Errors.Errors())
+	}
+	if public := c.Errors.ByType(ErrorTypePublic); len(public) != 1 || public[0] != err {
+		t.Errorf("public errors = %v", public.Errors())
+	}
+	if got, want := c.Errors.String(), "Error #01: first\n     Meta: some data\nError #02: second\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	wrapped := &Error{Err: errors.New("bind"), Type: ErrorTypeBind}
+	if c.Error(wrapped) != wrapped {
+		t.Error("*Error should be recorded as is")
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	en := New()
+	en.Use(ErrorHandler())
+	en.GET("/public", func(c *Context) {
+		c.AbortWithError(http.StatusBadRequest, errors.New("bad input")).SetType(ErrorTypePublic)
+	})
+	en.GET("/private", func(c *Context) {
+		_ = c.Error(errors.New("db down"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/public", nil)
+	req.Header.Set("Accept", "application/json")
+	en.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"errors":{"error":"bad input"}}` {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/private", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error" {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+}
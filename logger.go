@@ -0,0 +1,236 @@
+package rough
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultWriter 是Logger等中间件默认的输出
+var DefaultWriter io.Writer = os.Stdout
+
+type consoleColorModeValue int
+
+const (
+	autoColor consoleColorModeValue = iota
+	disableColor
+	forceColor
+)
+
+var consoleColorMode = autoColor
+
+const (
+	green   = "\033[97;42m"
+	white   = "\033[90;47m"
+	yellow  = "\033[90;43m"
+	red     = "\033[97;41m"
+	blue    = "\033[97;44m"
+	magenta = "\033[97;45m"
+	cyan    = "\033[97;46m"
+)
+
+// LoggerConfig 是Logger中间件的配置
+type LoggerConfig struct {
+	// Formatter 格式化一条日志，默认为defaultLogFormatter
+	Formatter LogFormatter
+
+	// Output 日志输出，默认为DefaultWriter
+	Output io.Writer
+
+	// SkipPaths 不记录日志的路径
+	SkipPaths []string
+}
+
+// LogFormatter 将LogFormatterParams格式化为一行日志
+type LogFormatter func(params LogFormatterParams) string
+
+// LogFormatterParams 是传给LogFormatter的参数
+type LogFormatterParams struct {
+	Request *http.Request
+
+	// TimeStamp 是请求处理完成的时间
+	TimeStamp time.Time
+	// StatusCode 是响应的状态码
+	StatusCode int
+	// Latency 是处理请求所用的时间
+	Latency time.Duration
+	// ClientIP 是客户端的IP
+	ClientIP string
+	// Method 是请求的方法
+	Method string
+	// Path 是请求的路径，包含query
+	Path string
+	// ErrorMessage 是处理请求过程中产生的错误
+	ErrorMessage string
+	// BodySize 是响应体的大小
+	BodySize int
+	// Keys 是请求上下文中设置的keys的副本
+	Keys map[string]any
+
+	isTerm bool
+}
+
+// StatusCodeColor 返回状态码对应的终端颜色
+func (p *LogFormatterParams) StatusCodeColor() string {
+	code := p.StatusCode
+	switch {
+	case code >= http.StatusContinue && code < http.StatusOK:
+		return white
+	case code >= http.StatusOK && code < http.StatusMultipleChoices:
+		return green
+	case code >= http.StatusMultipleChoices && code < http.StatusBadRequest:
+		return white
+	case code >= http.StatusBadRequest && code < http.StatusInternalServerError:
+		return yellow
+	default:
+		return red
+	}
+}
+
+// MethodColor 返回请求方法对应的终端颜色
+func (p *LogFormatterParams) MethodColor() string {
+	switch p.Method {
+	case http.MethodGet:
+		return blue
+	case http.MethodPost:
+		return cyan
+	case http.MethodPut:
+		return yellow
+	case http.MethodDelete:
+		return red
+	case http.MethodPatch:
+		return green
+	case http.MethodHead:
+		return magenta
+	case http.MethodOptions:
+		return white
+	default:
+		return resetColor
+	}
+}
+
+// ResetColor 返回重置终端颜色的控制字符
+func (p *LogFormatterParams) ResetColor() string {
+	return resetColor
+}
+
+// IsOutputColor 返回是否输出带颜色的日志
+func (p *LogFormatterParams) IsOutputColor() bool {
+	return consoleColorMode == forceColor || (consoleColorMode == autoColor && p.isTerm)
+}
+
+var defaultLogFormatter = func(param LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[ROUGH] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+// DisableConsoleColor 关闭日志颜色
+func DisableConsoleColor() {
+	consoleColorMode = disableColor
+}
+
+// ForceConsoleColor 强制输出带颜色的日志
+func ForceConsoleColor() {
+	consoleColorMode = forceColor
+}
+
+// Logger 返回一个记录请求日志的中间件，日志输出到DefaultWriter
+func Logger() HandleFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithFormatter 使用指定的格式输出日志
+func LoggerWithFormatter(f LogFormatter) HandleFunc {
+	return LoggerWithConfig(LoggerConfig{Formatter: f})
+}
+
+// LoggerWithWriter 将日志输出到out，notlogged中的路径不记录日志
+func LoggerWithWriter(out io.Writer, notlogged ...string) HandleFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out, SkipPaths: notlogged})
+}
+
+// LoggerWithConfig 使用conf创建Logger中间件
+func LoggerWithConfig(conf LoggerConfig) HandleFunc {
+	formatter := conf.Formatter
+	if formatter == nil {
+		formatter = defaultLogFormatter
+	}
+
+	out := conf.Output
+	if out == nil {
+		out = DefaultWriter
+	}
+
+	isTerm := isTerminal(out)
+
+	var skip map[string]struct{}
+	if length := len(conf.SkipPaths); length > 0 {
+		skip = make(map[string]struct{}, length)
+		for _, p := range conf.SkipPaths {
+			skip[p] = struct{}{}
+		}
+	}
+
+	return func(c *Context) {
+		start := time.Now()
+		path := c.R.URL.Path
+		raw := c.R.URL.RawQuery
+
+		c.Next()
+
+		if _, ok := skip[path]; ok {
+			return
+		}
+
+		param := LogFormatterParams{
+			Request: c.R,
+			isTerm:  isTerm,
+			Keys:    c.copyKeys(),
+		}
+		param.TimeStamp = time.Now()
+		param.Latency = param.TimeStamp.Sub(start)
+		param.ClientIP = c.ClientIP()
+		param.Method = c.R.Method
+		param.StatusCode = c.W.Status()
+		param.BodySize = c.W.Size()
+		param.ErrorMessage = c.Errors.ByType(ErrorTypePrivate).String()
+		if raw != "" {
+			path = path + "?" + raw
+		}
+		param.Path = path
+
+		fmt.Fprint(out, formatter(param))
+	}
+}
+
+// isTerminal 判断out是否为终端
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
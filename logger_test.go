@@ -0,0 +1,101 @@
+package rough
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	en := New()
+	en.Use(LoggerWithWriter(buf, "/skip"))
+	en.GET("/example", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	en.GET("/skip", func(c *Context) {})
+
+	en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/example?a=100", nil))
+	out := buf.String()
+	for _, want := range []string{"[ROUGH]", "200", "GET", "/example?a=100"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q does not contain %q", out, want)
+		}
+	}
+
+	buf.Reset()
+	en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/skip", nil))
+	if buf.Len() != 0 {
+		t.Errorf("skipped path logged: %q", buf.String())
+	}
+}
+
+func TestLoggerWithFormatter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	en := New()
+	en.Use(LoggerWithConfig(LoggerConfig{
+		Output: buf,
+		Formatter: func(p LogFormatterParams) string {
+			return p.Method + " " + p.Path + " " + http.StatusText(p.StatusCode) + "\n"
+		},
+	}))
+	en.POST("/form", func(c *Context) {
+		c.String(http.StatusCreated, "created")
+	})
+
+	en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/form", nil))
+	if out := buf.String(); out != "POST /form Created\n" {
+		t.Errorf("log = %q", out)
+	}
+}
+
+func TestLoggerKeysConcurrentSet(t *testing.T) {
+	en := New()
+	var stop chan struct{}
+	var wg sync.WaitGroup
+	// 在Logger之外等待goroutine结束，保证Logger读取Keys时仍有goroutine在Set
+	en.Use(func(c *Context) {
+		stop = make(chan struct{})
+		c.Next()
+		close(stop)
+		wg.Wait()
+	})
+	en.Use(LoggerWithConfig(LoggerConfig{
+		Output: io.Discard,
+		Formatter: func(p LogFormatterParams) string {
+			n := 0
+			for range p.Keys {
+				n++
+			}
+			return strconv.Itoa(n) + "\n"
+		},
+	}))
+	en.GET("/", func(c *Context) {
+		c.Set("user", "rough")
+		started := make(chan struct{})
+		wg.Add(1)
+		go func(stop chan struct{}) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					c.Set(strconv.Itoa(i%8), i)
+					if i == 0 {
+						close(started)
+					}
+				}
+			}
+		}(stop)
+		<-started
+	})
+	for i := 0; i < 20; i++ {
+		en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+}
@@ -0,0 +1,57 @@
+package rough
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// EnvRoughMode 是设置运行模式的环境变量
+const EnvRoughMode = "ROUGH_MODE"
+
+const (
+	// DebugMode 输出路由注册信息和各种警告
+	DebugMode = "debug"
+	// ReleaseMode 不输出调试信息
+	ReleaseMode = "release"
+	// TestMode 用于测试，不输出调试信息
+	TestMode = "test"
+)
+
+const (
+	debugCode = iota
+	releaseCode
+	testCode
+)
+
+var (
+	roughMode atomic.Int32
+	modeName  atomic.Value
+)
+
+func init() {
+	SetMode(os.Getenv(EnvRoughMode))
+}
+
+// SetMode 设置运行模式，value为空时使用DebugMode
+func SetMode(value string) {
+	if value == "" {
+		value = DebugMode
+	}
+
+	switch value {
+	case DebugMode:
+		roughMode.Store(debugCode)
+	case ReleaseMode:
+		roughMode.Store(releaseCode)
+	case TestMode:
+		roughMode.Store(testCode)
+	default:
+		panic("rough mode unknown: " + value + " (available mode: debug release test)")
+	}
+	modeName.Store(value)
+}
+
+// Mode 返回当前的运行模式
+func Mode() string {
+	return modeName.Load().(string)
+}
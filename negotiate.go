@@ -0,0 +1,138 @@
+package rough
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cainmusic/rough/binding"
+	"github.com/cainmusic/rough/render"
+)
+
+// Negotiate 是Context.Negotiate的参数
+type Negotiate struct {
+	// Offered 是服务端可以提供的格式，如binding.MIMEJSON
+	// 除了JSON、HTML和XML，其他格式使用render.Lookup找到的Render输出Data
+	Offered  []string
+	HTMLName string
+	HTMLData any
+	JSONData any
+	XMLData  any
+	// Data 在对应格式的数据为nil时使用
+	Data any
+}
+
+var errNotAcceptable = errors.New("the accepted formats are not offered by the server")
+
+// Negotiate 根据请求的Accept从config.Offered中选择格式输出，没有可用的格式时返回406
+func (c *Context) Negotiate(code int, config Negotiate) {
+	format := c.NegotiateFormat(config.Offered...)
+	switch filterFlags(format) {
+	case binding.MIMEJSON:
+		data := chooseData(config.JSONData, config.Data)
+		c.JSON(code, data)
+
+	case binding.MIMEHTML:
+		data := chooseData(config.HTMLData, config.Data)
+		c.HTML(code, config.HTMLName, data)
+
+	case binding.MIMEXML, binding.MIMEXML2:
+		data := chooseData(config.XMLData, config.Data)
+		c.XML(code, data)
+
+	case "":
+		c.AbortWithError(http.StatusNotAcceptable, errNotAcceptable).SetType(ErrorTypePublic)
+
+	default:
+		// 其他格式使用render中注册的Render输出
+		f, ok := render.Lookup(filterFlags(format))
+		if !ok {
+			c.AbortWithError(http.StatusNotAcceptable, errNotAcceptable).SetType(ErrorTypePublic)
+			return
+		}
+		c.Render(code, f(config.Data))
+	}
+}
+
+// NegotiateFormat 按照Accept中的q值，返回offered中最合适的格式，q值相同时按offered的顺序
+// 每个格式的q值取自最具体的匹配项，所以"application/json;q=0, */*"会排除json
+// 请求没有Accept时返回offered[0]，没有可以接受的格式时返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	assert1(len(offered) > 0, "you must provide at least one offer")
+
+	accepted := parseAccept(c.GetHeader("Accept"))
+	if len(accepted) == 0 {
+		return offered[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		if q := quality(accepted, filterFlags(offer)); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// SetAccepted 设置可以接受的格式，用于测试或者覆盖请求的Accept
+func (c *Context) SetAccepted(formats ...string) {
+	c.R.Header.Set("Accept", strings.Join(formats, ", "))
+}
+
+type acceptRange struct {
+	mime string
+	q    float64
+}
+
+// parseAccept 解析Accept中的每一项及其q值
+func parseAccept(acceptHeader string) []acceptRange {
+	parts := strings.Split(acceptHeader, ",")
+	accepts := make([]acceptRange, 0, len(parts))
+	for _, part := range parts {
+		mime, params, _ := strings.Cut(part, ";")
+		mime = strings.ToLower(strings.TrimSpace(mime))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		accepts = append(accepts, acceptRange{mime, q})
+	}
+	return accepts
+}
+
+// quality 返回offer在accepts中最具体的匹配项的q值，没有匹配时返回0
+func quality(accepts []acceptRange, offer string) float64 {
+	offer = strings.ToLower(offer)
+	q, specificity := 0.0, 0
+	for _, a := range accepts {
+		s := 0
+		switch {
+		case a.mime == offer:
+			s = 3
+		case strings.HasSuffix(a.mime, "/*") && strings.HasPrefix(offer, a.mime[:len(a.mime)-1]):
+			s = 2
+		case a.mime == "*/*" || a.mime == "*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = a.q, s
+		}
+	}
+	return q
+}
+
+func chooseData(custom, wildcard any) any {
+	if custom != nil {
+		return custom
+	}
+	return wildcard
+}
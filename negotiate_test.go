@@ -0,0 +1,87 @@
+package rough
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cainmusic/rough/binding"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		accept  string
+		offered []string
+		want    string
+	}{
+		{"", []string{binding.MIMEJSON, binding.MIMEXML}, binding.MIMEJSON},
+		{"application/xml", []string{binding.MIMEJSON, binding.MIMEXML}, binding.MIMEXML},
+		{"text/html;q=0.8, application/json;q=0.9", []string{binding.MIMEHTML, binding.MIMEJSON}, binding.MIMEJSON},
+		{"text/*, application/json;q=0.5", []string{binding.MIMEJSON, binding.MIMEHTML}, binding.MIMEHTML},
+		{"*/*", []string{binding.MIMEXML, binding.MIMEJSON}, binding.MIMEXML},
+		{"application/json;q=0, */*;q=0.1", []string{binding.MIMEJSON, binding.MIMEXML}, binding.MIMEXML},
+		{"image/png", []string{binding.MIMEJSON}, ""},
+	}
+	for _, tc := range cases {
+		c := &Context{R: httptest.NewRequest(http.MethodGet, "/", nil)}
+		if tc.accept != "" {
+			c.R.Header.Set("Accept", tc.accept)
+		}
+		if got := c.NegotiateFormat(tc.offered...); got != tc.want {
+			t.Errorf("Accept %q: got %q, want %q", tc.accept, got, tc.want)
+		}
+	}
+}
+
+type negotiateData struct {
+	Name string
+}
+
+func TestNegotiate(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered:  []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2},
+			JSONData: H{"name": "rough"},
+			XMLData:  negotiateData{"rough"},
+			Data:     "data",
+		})
+	})
+
+	cases := []struct {
+		accept, body string
+		code         int
+	}{
+		{"application/json", `{"name":"rough"}`, http.StatusOK},
+		{"application/xml", `<negotiateData><Name>rough</Name></negotiateData>`, http.StatusOK},
+		{"text/xml", `<negotiateData><Name>rough</Name></negotiateData>`, http.StatusOK},
+		{"text/csv", "", http.StatusNotAcceptable},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tc.accept)
+		en.ServeHTTP(w, req)
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Errorf("Accept %q: got %d %q", tc.accept, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNegotiateRegistry(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{binding.MIMEJSON, binding.MIMEYAML},
+			Data:    H{"name": "rough"},
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/yaml")
+	en.ServeHTTP(w, req)
+	if w.Body.String() != "name: rough\n" || w.Header().Get("Content-Type") != "application/yaml; charset=utf-8" {
+		t.Errorf("got %q %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
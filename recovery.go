@@ -0,0 +1,156 @@
+package rough
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultErrorWriter 是Recovery等中间件默认的错误输出
+var DefaultErrorWriter io.Writer = os.Stderr
+
+var (
+	dunno     = []byte("???")
+	centerDot = []byte("·")
+	dot       = []byte(".")
+	slash     = []byte("/")
+)
+
+// RecoveryFunc 处理中间件捕获到的panic，err为recover()的返回值
+type RecoveryFunc func(c *Context, err any)
+
+// Recovery 返回一个捕获panic的中间件，发生panic时记录调用栈并返回500
+func Recovery() HandleFunc {
+	return RecoveryWithWriter(DefaultErrorWriter)
+}
+
+// CustomRecovery 和Recovery相同，但使用handle处理捕获到的panic
+func CustomRecovery(handle RecoveryFunc) HandleFunc {
+	return RecoveryWithWriter(DefaultErrorWriter, handle)
+}
+
+// RecoveryWithWriter 将panic信息写入out，out为nil时不输出日志
+func RecoveryWithWriter(out io.Writer, recovery ...RecoveryFunc) HandleFunc {
+	if len(recovery) > 0 {
+		return CustomRecoveryWithWriter(out, recovery[0])
+	}
+	return CustomRecoveryWithWriter(out, defaultHandleRecovery)
+}
+
+// CustomRecoveryWithWriter 将panic信息写入out，并使用handle处理捕获到的panic
+func CustomRecoveryWithWriter(out io.Writer, handle RecoveryFunc) HandleFunc {
+	var logger *log.Logger
+	if out != nil {
+		logger = log.New(out, "\n\n\x1b[31m", log.LstdFlags)
+	}
+	return func(c *Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				// 客户端已经断开的连接不需要返回500，也写不进去
+				brokenPipe := isBrokenPipe(err)
+				if logger != nil {
+					stack := stack(3)
+					httpRequest, _ := httputil.DumpRequest(c.R, false)
+					headers := strings.Split(string(httpRequest), "\r\n")
+					for idx, header := range headers {
+						current := strings.Split(header, ":")
+						if current[0] == "Authorization" {
+							headers[idx] = current[0] + ": *"
+						}
+					}
+					headersToStr := strings.Join(headers, "\r\n")
+					if brokenPipe {
+						logger.Printf("%s\n%s%s", err, headersToStr, resetColor)
+					} else {
+						logger.Printf("[Recovery] %s panic recovered:\n%s\n%s\n%s%s",
+							time.Now().Format("2006/01/02 - 15:04:05"), headersToStr, err, stack, resetColor)
+					}
+				}
+				if brokenPipe {
+					_ = c.Error(err.(error))
+					c.Abort()
+					return
+				}
+				handle(c, err)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func defaultHandleRecovery(c *Context, _ any) {
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+const resetColor = "\033[0m"
+
+func isBrokenPipe(err any) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(e.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// stack 返回格式化后的调用栈，跳过skip层
+func stack(skip int) []byte {
+	buf := new(bytes.Buffer)
+	var lines [][]byte
+	var lastFile string
+	for i := skip; ; i++ {
+		pc, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		fmt.Fprintf(buf, "%s:%d (0x%x)\n", file, line, pc)
+		if file != lastFile {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			lines = bytes.Split(data, []byte{'\n'})
+			lastFile = file
+		}
+		fmt.Fprintf(buf, "\t%s: %s\n", function(pc), source(lines, line))
+	}
+	return buf.Bytes()
+}
+
+// source 返回第n行去掉首尾空白的源码
+func source(lines [][]byte, n int) []byte {
+	n-- // 调用栈中的行号从1开始，切片从0开始
+	if n < 0 || n >= len(lines) {
+		return dunno
+	}
+	return bytes.TrimSpace(lines[n])
+}
+
+// function 返回pc所在函数的名称，去掉包路径
+func function(pc uintptr) []byte {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return dunno
+	}
+	name := []byte(fn.Name())
+	if lastSlash := bytes.LastIndex(name, slash); lastSlash >= 0 {
+		name = name[lastSlash+1:]
+	}
+	if period := bytes.Index(name, dot); period >= 0 {
+		name = name[period+1:]
+	}
+	name = bytes.ReplaceAll(name, centerDot, dot)
+	return name
+}
@@ -0,0 +1,60 @@
+package rough
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	buf := new(bytes.Buffer)
+	en := New()
+	en.Use(RecoveryWithWriter(buf))
+	en.GET("/panic", func(c *Context) {
+		panic("oops")
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "panic recovered") || !strings.Contains(out, `panic("oops")`) {
+		t.Errorf("unexpected log output:\n%s", out)
+	}
+}
+
+func TestCustomRecovery(t *testing.T) {
+	en := New()
+	en.Use(CustomRecoveryWithWriter(nil, func(c *Context, err any) {
+		c.String(http.StatusBadRequest, "recovered: %v", err)
+	}))
+	en.GET("/panic", func(c *Context) {
+		panic("oops")
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusBadRequest || w.Body.String() != "recovered: oops" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	en := New()
+	en.Use(RecoveryWithWriter(nil))
+	en.GET("/pipe", func(c *Context) {
+		c.Status(http.StatusNoContent)
+		panic(syscall.EPIPE)
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pipe", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+}
@@ -1,8 +1,11 @@
 package render
 
 import (
+	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
+	"path/filepath"
 )
 
 type Delims struct {
@@ -16,7 +19,8 @@ type HTMLRender interface {
 
 type HTMLProduction struct {
 	Template *template.Template
-	Delims   Delims
+	// Delims 是解析Template时使用的分隔符，之后通过Parse添加的模板也使用它
+	Delims Delims
 }
 
 func (r HTMLProduction) Instance(name string, data any) Render {
@@ -27,6 +31,124 @@ func (r HTMLProduction) Instance(name string, data any) Render {
 	}
 }
 
+// Parse 使用Delims解析text并添加到Template中
+func (r HTMLProduction) Parse(name, text string) (*template.Template, error) {
+	return r.Template.New(name).Delims(r.Delims.Left, r.Delims.Right).Parse(text)
+}
+
+// HTMLDebug 在每次渲染时重新解析Files、Glob或FileSystem，修改模板后不需要重启服务，只应在debug模式下使用
+type HTMLDebug struct {
+	Files      []string
+	Glob       string
+	FileSystem fs.FS
+	Patterns   []string
+	Delims     Delims
+	FuncMap    template.FuncMap
+}
+
+func (r HTMLDebug) Instance(name string, data any) Render {
+	return HTML{
+		Template: r.loadTemplate(),
+		Name:     name,
+		Data:     data,
+	}
+}
+
+func (r HTMLDebug) loadTemplate() *template.Template {
+	if r.FuncMap == nil {
+		r.FuncMap = template.FuncMap{}
+	}
+	templ := template.New("").Delims(r.Delims.Left, r.Delims.Right).Funcs(r.FuncMap)
+	if len(r.Files) > 0 {
+		return template.Must(templ.ParseFiles(r.Files...))
+	}
+	if r.Glob != "" {
+		return template.Must(templ.ParseGlob(r.Glob))
+	}
+	if r.FileSystem != nil && len(r.Patterns) > 0 {
+		return template.Must(templ.ParseFS(r.FileSystem, r.Patterns...))
+	}
+	panic("the HTML debug render was created without files, glob pattern or file system")
+}
+
+// HTMLMulti 为每个页面保存一个独立的模板集合，通常由layout、partials和页面本身组成
+// 不同页面中同名的block（如"content"）互不影响，渲染时name为页面名，执行集合中的第一个模板
+type HTMLMulti struct {
+	Delims  Delims
+	FuncMap template.FuncMap
+	// Debug 为true时每次渲染都重新解析页面的文件
+	// 通过Engine.SetHTMLRender设置时会跟随debug模式，直接赋值给Engine.HTMLRender时需要手动设置
+	Debug bool
+
+	templates map[string]*template.Template
+	loaders   map[string]func() (*template.Template, error)
+}
+
+// NewHTMLMulti 返回一个空的HTMLMulti
+func NewHTMLMulti() *HTMLMulti {
+	return &HTMLMulti{
+		Delims:    Delims{Left: "{{", Right: "}}"},
+		FuncMap:   template.FuncMap{},
+		templates: make(map[string]*template.Template),
+		loaders:   make(map[string]func() (*template.Template, error)),
+	}
+}
+
+// Add 直接添加一个已经解析好的页面模板
+func (r *HTMLMulti) Add(name string, templ *template.Template) {
+	r.templates[name] = templ
+	delete(r.loaders, name)
+}
+
+// AddFromFiles 使用files组成名为name的页面，第一个文件一般为layout
+func (r *HTMLMulti) AddFromFiles(name string, files ...string) *template.Template {
+	if len(files) == 0 {
+		panic(fmt.Sprintf("render: no files given for html page %q", name))
+	}
+	return r.add(name, func() (*template.Template, error) {
+		return r.newTemplate(files[0]).ParseFiles(files...)
+	})
+}
+
+// AddFromGlob 使用匹配glob的文件组成名为name的页面，按照文件名排序后的第一个文件被执行
+func (r *HTMLMulti) AddFromGlob(name, glob string) *template.Template {
+	return r.add(name, func() (*template.Template, error) {
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("html/template: pattern matches no files: %#q", glob)
+		}
+		return r.newTemplate(files[0]).ParseFiles(files...)
+	})
+}
+
+func (r *HTMLMulti) newTemplate(first string) *template.Template {
+	return template.New(filepath.Base(first)).Delims(r.Delims.Left, r.Delims.Right).Funcs(r.FuncMap)
+}
+
+func (r *HTMLMulti) add(name string, load func() (*template.Template, error)) *template.Template {
+	templ := template.Must(load())
+	r.templates[name] = templ
+	r.loaders[name] = load
+	return templ
+}
+
+func (r *HTMLMulti) Instance(name string, data any) Render {
+	templ, ok := r.templates[name]
+	if !ok {
+		return htmlError{fmt.Errorf("render: html page %q is undefined", name)}
+	}
+	if load, ok := r.loaders[name]; ok && r.Debug {
+		templ = template.Must(load())
+	}
+	return HTML{
+		Template: templ,
+		Data:     data,
+	}
+}
+
 type HTML struct {
 	Template *template.Template
 	Name     string
@@ -46,3 +168,16 @@ func (r HTML) Render(w http.ResponseWriter) error {
 func (r HTML) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, htmlContentType)
 }
+
+// htmlError 用于HTMLRender找不到模板时返回错误
+type htmlError struct {
+	err error
+}
+
+func (r htmlError) Render(http.ResponseWriter) error {
+	return r.err
+}
+
+func (r htmlError) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
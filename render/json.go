@@ -1,8 +1,14 @@
 package render
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type JSON struct {
@@ -24,3 +30,146 @@ func (r JSON) Render(w http.ResponseWriter) error {
 func (r JSON) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, jsonContentType)
 }
+
+// IndentedJSON 输出带缩进的JSON，便于调试
+type IndentedJSON struct {
+	Data any
+}
+
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// SecureJSON 在JSON数组前加上Prefix，防止JSON劫持
+type SecureJSON struct {
+	Prefix string
+	Data   any
+}
+
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	// 只有数组可以被<script>直接执行并劫持
+	if bytes.HasPrefix(jsonBytes, []byte("[")) && bytes.HasSuffix(jsonBytes, []byte("]")) {
+		if _, err = w.Write([]byte(r.Prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// JsonpJSON 输出Callback(JSON);，Callback只能包含字母、数字、_、$、.和[]
+type JsonpJSON struct {
+	Callback string
+	Data     any
+}
+
+var jsonpContentType = []string{"application/javascript; charset=utf-8"}
+
+var regJSONPCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$.\[\]]*$`)
+
+// ErrInvalidCallback 表示JSONP的callback包含不允许的字符
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+// ValidCallback 判断callback是否是合法的JSONP函数名
+func ValidCallback(callback string) bool {
+	return regJSONPCallback.MatchString(callback)
+}
+
+func (r JsonpJSON) Render(w http.ResponseWriter) error {
+	// 在设置Content-Type之前校验，避免以javascript类型返回空的响应
+	if r.Callback != "" && !ValidCallback(r.Callback) {
+		return ErrInvalidCallback
+	}
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	if r.Callback == "" {
+		_, err = w.Write(jsonBytes)
+		return err
+	}
+	// 开头的/**/用于防止Rosetta Flash攻击
+	buf := make([]byte, 0, len(r.Callback)+len(jsonBytes)+7)
+	buf = append(buf, "/**/"...)
+	buf = append(buf, r.Callback...)
+	buf = append(buf, '(')
+	buf = append(buf, jsonBytes...)
+	buf = append(buf, ");"...)
+	_, err = w.Write(buf)
+	return err
+}
+
+func (r JsonpJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonpContentType)
+}
+
+// AsciiJSON 将非ASCII字符转义为\uXXXX
+type AsciiJSON struct {
+	Data any
+}
+
+func (r AsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	buffer.Grow(len(jsonBytes))
+	for _, ch := range string(jsonBytes) {
+		if ch < utf8.RuneSelf {
+			buffer.WriteByte(byte(ch))
+			continue
+		}
+		// 超出BMP的字符需要转为UTF-16代理对
+		if r1, r2 := utf16.EncodeRune(ch); r1 != utf8.RuneError {
+			fmt.Fprintf(&buffer, "\\u%04x\\u%04x", r1, r2)
+			continue
+		}
+		fmt.Fprintf(&buffer, "\\u%04x", ch)
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+func (r AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// PureJSON 不转义HTML字符，如<、>和&
+type PureJSON struct {
+	Data any
+}
+
+func (r PureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+func (r PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
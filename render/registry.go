@@ -0,0 +1,41 @@
+package render
+
+import (
+	"strings"
+	"sync"
+)
+
+// Factory 使用data创建一个Render
+type Factory func(data any) Render
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"application/json":       func(data any) Render { return JSON{Data: data} },
+		"application/xml":        func(data any) Render { return XML{Data: data} },
+		"text/xml":               func(data any) Render { return XML{Data: data} },
+		"application/yaml":       func(data any) Render { return YAML{Data: data} },
+		"application/x-yaml":     func(data any) Render { return YAML{Data: data} },
+		"application/toml":       func(data any) Render { return TOML{Data: data} },
+		"application/x-protobuf": func(data any) Render { return ProtoBuf{Data: data} },
+	}
+)
+
+// Register 将MIME类型映射到f，已存在的类型会被覆盖
+// 内容协商时会通过Lookup找到对应的Render
+func Register(mimeType string, f Factory) {
+	if f == nil {
+		panic("render: Register factory is nil")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(mimeType)] = f
+}
+
+// Lookup 返回MIME类型对应的Factory
+func Lookup(mimeType string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[strings.ToLower(mimeType)]
+	return f, ok
+}
@@ -9,10 +9,28 @@ type Render interface {
 	WriteContentType(http.ResponseWriter)
 }
 
+var (
+	_ HTMLRender = HTMLProduction{}
+	_ HTMLRender = HTMLDebug{}
+	_ HTMLRender = (*HTMLMulti)(nil)
+)
+
 var (
 	_ Render = String{}
 	_ Render = JSON{}
+	_ Render = IndentedJSON{}
+	_ Render = SecureJSON{}
+	_ Render = JsonpJSON{}
+	_ Render = AsciiJSON{}
+	_ Render = PureJSON{}
 	_ Render = HTML{}
+	_ Render = XML{}
+	_ Render = Data{}
+	_ Render = Reader{}
+	_ Render = YAML{}
+	_ Render = TOML{}
+	_ Render = ProtoBuf{}
+	_ Render = SSEvent{}
 )
 
 func writeContentType(w http.ResponseWriter, value []string) {
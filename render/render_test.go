@@ -0,0 +1,234 @@
+package render
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRenderFormats(t *testing.T) {
+	data := map[string]any{"name": "rough"}
+	cases := []struct {
+		r           Render
+		contentType string
+		body        string
+	}{
+		{YAML{Data: data}, "application/yaml; charset=utf-8", "name: rough\n"},
+		{TOML{Data: data}, "application/toml; charset=utf-8", "name = 'rough'\n"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := tc.r.Render(w); err != nil {
+			t.Errorf("%T: %v", tc.r, err)
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != tc.contentType {
+			t.Errorf("%T: Content-Type = %q, want %q", tc.r, ct, tc.contentType)
+		}
+		if body := w.Body.String(); body != tc.body {
+			t.Errorf("%T: body = %q, want %q", tc.r, body, tc.body)
+		}
+	}
+}
+
+func TestRenderProtoBuf(t *testing.T) {
+	msg := wrapperspb.String("rough")
+	w := httptest.NewRecorder()
+	if err := (ProtoBuf{Data: msg}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	var got wrapperspb.StringValue
+	if err := proto.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Value != "rough" {
+		t.Errorf("got %v, %v", got.Value, err)
+	}
+
+	if err := (ProtoBuf{Data: "not a message"}).Render(httptest.NewRecorder()); err == nil {
+		t.Error("expected error for non proto.Message")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	f, ok := Lookup("Application/YAML")
+	if !ok {
+		t.Fatal("yaml should be registered")
+	}
+	if _, isYAML := f(nil).(YAML); !isYAML {
+		t.Errorf("Lookup(yaml) returned %T", f(nil))
+	}
+
+	Register("text/csv", func(data any) Render { return String{Format: "%v", Data: []any{data}} })
+	if _, ok := Lookup("text/csv"); !ok {
+		t.Error("text/csv should be registered")
+	}
+}
+
+func TestRenderJSONVariants(t *testing.T) {
+	cases := []struct {
+		r    Render
+		body string
+	}{
+		{IndentedJSON{Data: map[string]int{"a": 1}}, "{\n    \"a\": 1\n}"},
+		{SecureJSON{Prefix: "while(1);", Data: []int{1, 2}}, "while(1);[1,2]"},
+		{SecureJSON{Prefix: "while(1);", Data: map[string]int{"a": 1}}, `{"a":1}`},
+		{JsonpJSON{Callback: "cb", Data: map[string]int{"a": 1}}, `/**/cb({"a":1});`},
+		{JsonpJSON{Data: map[string]int{"a": 1}}, `{"a":1}`},
+		{AsciiJSON{Data: map[string]string{"lang": "中文<b>😀"}}, `{"lang":"\u4e2d\u6587\u003cb\u003e\ud83d\ude00"}`},
+		{PureJSON{Data: map[string]string{"html": "<b>"}}, "{\"html\":\"<b>\"}\n"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := tc.r.Render(w); err != nil {
+			t.Errorf("%T: %v", tc.r, err)
+			continue
+		}
+		if body := w.Body.String(); body != tc.body {
+			t.Errorf("%T: body = %q, want %q", tc.r, body, tc.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	err := (JsonpJSON{Callback: "alert(1)//", Data: 1}).Render(w)
+	if err != ErrInvalidCallback {
+		t.Errorf("err = %v, want ErrInvalidCallback", err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Content-Type set for invalid callback: %q", ct)
+	}
+}
+
+func TestRenderSSEvent(t *testing.T) {
+	cases := []struct {
+		event SSEvent
+		want  string
+	}{
+		{SSEvent{Event: "msg", Data: "hello"}, "event: msg\ndata: hello\n\n"},
+		{SSEvent{Id: "1", Retry: 3000, Data: "a\nb\r\nc"}, "id: 1\nretry: 3000\ndata: a\ndata: b\ndata: c\n\n"},
+		{SSEvent{Event: "job\nx", Data: map[string]int{"done": 3}}, "event: jobx\ndata: {\"done\":3}\n\n"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := tc.event.Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Body.String() != tc.want {
+			t.Errorf("body = %q, want %q", w.Body.String(), tc.want)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+	}
+}
+
+func renderHTML(t *testing.T, r HTMLRender, name string, data any) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := r.Instance(name, data).Render(w); err != nil {
+		t.Fatalf("render %q: %v", name, err)
+	}
+	return w.Body.String()
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTMLDebug(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	writeFile(t, page, `v1 {{.}}`)
+
+	r := HTMLDebug{Glob: filepath.Join(dir, "*.html"), Delims: Delims{Left: "{{", Right: "}}"}}
+	if got := renderHTML(t, r, "page.html", "a"); got != "v1 a" {
+		t.Errorf("got %q", got)
+	}
+	writeFile(t, page, `v2 {{.}}`)
+	if got := renderHTML(t, r, "page.html", "a"); got != "v2 a" {
+		t.Errorf("template not reloaded, got %q", got)
+	}
+}
+
+func TestHTMLMulti(t *testing.T) {
+	dir := t.TempDir()
+	layout := filepath.Join(dir, "layout.html")
+	nav := filepath.Join(dir, "nav.html")
+	index := filepath.Join(dir, "index.html")
+	about := filepath.Join(dir, "about.html")
+	writeFile(t, layout, `<p>{{template "nav"}}</p>{{block "content" .}}{{end}}`)
+	writeFile(t, nav, `{{define "nav"}}nav{{end}}`)
+	writeFile(t, index, `{{define "content"}}index {{.}}{{end}}`)
+	writeFile(t, about, `{{define "content"}}about {{upper .}}{{end}}`)
+
+	r := NewHTMLMulti()
+	r.FuncMap = template.FuncMap{"upper": strings.ToUpper}
+	r.AddFromFiles("index", layout, nav, index)
+	r.AddFromFiles("about", layout, nav, about)
+
+	if got := renderHTML(t, r, "index", "x"); got != "<p>nav</p>index x" {
+		t.Errorf("index = %q", got)
+	}
+	if got := renderHTML(t, r, "about", "x"); got != "<p>nav</p>about X" {
+		t.Errorf("about = %q", got)
+	}
+	func() {
+		defer func() {
+			if msg, _ := recover().(string); !strings.Contains(msg, `no files given for html page "empty"`) {
+				t.Errorf("AddFromFiles without files: recover() = %q", msg)
+			}
+		}()
+		r.AddFromFiles("empty")
+	}()
+	if err := r.Instance("missing", nil).Render(httptest.NewRecorder()); err == nil {
+		t.Error("expected error for undefined page")
+	}
+
+	writeFile(t, index, `{{define "content"}}new {{.}}{{end}}`)
+	if got := renderHTML(t, r, "index", "x"); got != "<p>nav</p>index x" {
+		t.Errorf("reloaded without Debug: %q", got)
+	}
+	r.Debug = true
+	if got := renderHTML(t, r, "index", "x"); got != "<p>nav</p>new x" {
+		t.Errorf("not reloaded with Debug: %q", got)
+	}
+}
+
+func TestHTMLProductionParse(t *testing.T) {
+	r := HTMLProduction{Template: template.New(""), Delims: Delims{Left: "<%", Right: "%>"}}
+	if _, err := r.Parse("page", `<% . %>`); err != nil {
+		t.Fatal(err)
+	}
+	if got := renderHTML(t, r, "page", "rough"); got != "rough" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRenderReaderKeepsHeaders(t *testing.T) {
+	headers := map[string]string{"X-Extra": "1"}
+
+	w := httptest.NewRecorder()
+	if err := (Reader{ContentType: "text/plain", ContentLength: 5, Reader: strings.NewReader("hello"), Headers: headers}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Length") != "5" || w.Header().Get("X-Extra") != "1" {
+		t.Errorf("headers = %v", w.Header())
+	}
+	if _, ok := headers["Content-Length"]; ok || len(headers) != 1 {
+		t.Errorf("caller's headers modified: %v", headers)
+	}
+
+	w = httptest.NewRecorder()
+	if err := (Reader{ContentType: "text/plain", ContentLength: -1, Reader: strings.NewReader("hello world"), Headers: headers}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("stale Content-Length %q", cl)
+	}
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SSEvent 是一条Server-Sent Events消息
+// Data为string或[]byte时原样输出，其他类型编码为JSON，多行数据拆分为多个data字段
+type SSEvent struct {
+	Id    string
+	Event string
+	Retry uint
+	Data  any
+}
+
+var sseContentType = []string{"text/event-stream"}
+
+var sseFieldReplacer = strings.NewReplacer("\r\n", "", "\r", "", "\n", "")
+
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return r.Encode(w)
+}
+
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	writeContentType(w, sseContentType)
+	if _, exist := header["Cache-Control"]; !exist {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+// Encode 按照事件流格式将消息写入w，不设置任何响应头
+func (r SSEvent) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	if r.Id != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(sseFieldReplacer.Replace(r.Id))
+		buf.WriteByte('\n')
+	}
+	if r.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(sseFieldReplacer.Replace(r.Event))
+		buf.WriteByte('\n')
+	}
+	if r.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatUint(uint64(r.Retry), 10))
+		buf.WriteByte('\n')
+	}
+
+	data, err := sseData(r.Data)
+	if err != nil {
+		return err
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func sseData(data any) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
@@ -1,20 +1,36 @@
 package rough
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"path"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cainmusic/rough/render"
+	"github.com/cainmusic/rough/websocket"
 )
 
 type HandleFunc func(*Context)
 
 const defaultMultipartMemory = 32 << 20
 
-var default404Body = []byte("404 page not found")
+const defaultAddress = ":8888"
+
+var (
+	default404Body = []byte("404 page not found")
+	default405Body = []byte("405 method not allowed")
+	default413Body = []byte("413 request entity too large")
+)
 
 var regSafePrefix = regexp.MustCompile("[^a-zA-Z0-9/-]+")
 var regRemoveRepeatedChar = regexp.MustCompile("/{2,}")
@@ -26,15 +42,65 @@ type Engine struct {
 	RedirectTrailingSlash bool
 	RedirectFixedPath     bool
 
+	// 请求的方法没有匹配的路由，但其他方法下存在该路径时，
+	// 返回405并在Allow头中列出这些方法，否则按404处理
+	HandleMethodNotAllowed bool
+
+	// 没有注册OPTIONS路由时，自动响应OPTIONS请求并在Allow头中列出可用的方法
+	HandleOPTIONS bool
+	// 没有注册HEAD路由时，HEAD请求使用GET路由处理并丢弃响应体
+	HandleHEAD bool
+
+	// MaxMultipartMemory 是解析multipart表单时保存在内存中的最大字节数，超出的部分写入临时文件
+	MaxMultipartMemory int64
+	// MaxRequestBodySize 限制请求体的大小，超出时返回413，0表示不限制
+	MaxRequestBodySize int64
+
+	// ForwardedByClientIP 为true时，来自可信代理的请求会从RemoteIPHeaders中解析客户端IP
+	ForwardedByClientIP bool
+	// RemoteIPHeaders 是ClientIP依次读取的请求头
+	RemoteIPHeaders []string
+	// TrustedPlatform 是由平台设置的客户端IP请求头，如PlatformCloudflare
+	// 设置后ClientIP优先读取该请求头，只应在服务只能通过该平台访问时使用
+	TrustedPlatform string
+
+	trustedCIDRs []*net.IPNet
+
+	// 为true时Context的Deadline、Done、Err和Value会使用请求的context
+	ContextWithFallback bool
+
 	delims     render.Delims
+	htmlLoaded bool
 	HTMLRender render.HTMLRender
 	FuncMap    template.FuncMap
 
+	// Upgrader 是Context.Upgrade使用的WebSocket配置
+	Upgrader websocket.Upgrader
+
+	// Logger 用于输出engine内部的诊断日志，为nil时使用slog.Default()
+	Logger *slog.Logger
+
+	secureJSONPrefix string
+
 	maxParams   uint16
 	maxSections uint16
 
-	noRoute    []HandleFunc
-	allNoRoute []HandleFunc
+	noRoute     []HandleFunc
+	allNoRoute  []HandleFunc
+	noMethod    []HandleFunc
+	allNoMethod []HandleFunc
+
+	// 以下超时配置在每次Run*时用于创建http.Server，0表示不限制
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	srvMu        sync.Mutex
+	srvs         []*http.Server
+	shuttingDown int
+
+	pool sync.Pool
 }
 
 type RouteInfo struct {
@@ -48,6 +114,7 @@ type RouteInfo struct {
 type RoutesInfo []RouteInfo
 
 func New() *Engine {
+	debugPrintWARNINGNew()
 	en := &Engine{
 		RouterGroup: RouterGroup{
 			Handlers: nil,
@@ -55,45 +122,124 @@ func New() *Engine {
 		},
 		trees: make(methodTrees, 0, 9),
 
-		RedirectTrailingSlash: true,
-		RedirectFixedPath:     false,
-
-		FuncMap: template.FuncMap{},
-		delims:  render.Delims{Left: "{{", Right: "}}"},
+		RedirectTrailingSlash:  true,
+		RedirectFixedPath:      false,
+		HandleMethodNotAllowed: false,
+		HandleOPTIONS:          false,
+		HandleHEAD:             true,
+		MaxMultipartMemory:     defaultMultipartMemory,
+		ForwardedByClientIP:    true,
+		RemoteIPHeaders:        []string{"X-Forwarded-For", "X-Real-IP"},
+
+		FuncMap:          template.FuncMap{},
+		secureJSONPrefix: "while(1);",
+		delims:           render.Delims{Left: "{{", Right: "}}"},
 	}
 	en.RouterGroup.engine = en
+	en.pool.New = func() any {
+		return en.allocateContext()
+	}
 	return en
 }
 
+// Default 返回一个已经使用了Logger和Recovery中间件的Engine
+func Default() *Engine {
+	debugPrintWARNINGDefault()
+	en := New()
+	en.Use(Logger(), Recovery())
+	return en
+}
+
+func (en *Engine) allocateContext() *Context {
+	params := make(Params, 0, en.maxParams)
+	skippedNodes := make([]skippedNode, 0, en.maxSections)
+	return &Context{engine: en, params: &params, skippedNodes: &skippedNodes}
+}
+
 func (en *Engine) Use(fs ...HandleFunc) {
 	en.RouterGroup.Use(fs...)
 	en.rebuild404Handlers()
+	en.rebuild405Handlers()
 }
 
-func (en *Engine) LoadHTMLGlob(pattern string) {
-	templ := template.Must(
-		template.New("").
-			Delims(en.delims.Left, en.delims.Right).
-			Funcs(en.FuncMap).
-			ParseGlob(pattern))
+// 模板的分隔符和FuncMap在加载时生效，因此Delims和SetFuncMap需要在LoadHTML*和SetHTMLTemplate之前调用
+
+// Delims 设置加载模板时使用的分隔符，默认为"{{"和"}}"
+func (en *Engine) Delims(left, right string) *Engine {
+	en.warnHTMLLoaded("Delims")
+	en.delims = render.Delims{Left: left, Right: right}
+	return en
+}
+
+// SetFuncMap 设置加载模板时使用的FuncMap
+func (en *Engine) SetFuncMap(funcMap template.FuncMap) {
+	en.warnHTMLLoaded("SetFuncMap")
+	en.FuncMap = funcMap
+}
+
+// warnHTMLLoaded 在模板已经加载后修改模板配置时输出警告，此时的修改对已加载的模板无效
+func (en *Engine) warnHTMLLoaded(method string) {
+	if en.htmlLoaded {
+		en.logger().Warn(method + " called after templates were loaded, it has no effect on them; call it before LoadHTMLGlob, LoadHTMLFiles, LoadHTMLFS or SetHTMLTemplate")
+	}
+}
+
+func (en *Engine) newTemplate() *template.Template {
+	return template.New("").Delims(en.delims.Left, en.delims.Right).Funcs(en.FuncMap)
+}
+
+// loadHTML 在debug模式下使用debugRender在每次渲染时重新加载，否则使用已经解析好的templ
+func (en *Engine) loadHTML(templ *template.Template, debugRender render.HTMLDebug) {
+	if IsDebugging() {
+		debugPrintLoadTemplate(templ)
+		debugRender.Delims = en.delims
+		debugRender.FuncMap = en.FuncMap
+		en.HTMLRender = debugRender
+		en.htmlLoaded = true
+		return
+	}
 	en.SetHTMLTemplate(templ)
 }
 
+// LoadHTMLGlob 加载匹配pattern的模板，debug模式下每次渲染时都会重新加载
+func (en *Engine) LoadHTMLGlob(pattern string) {
+	templ := template.Must(en.newTemplate().ParseGlob(pattern))
+	en.loadHTML(templ, render.HTMLDebug{Glob: pattern})
+}
+
+// LoadHTMLFiles 加载files中的模板，debug模式下每次渲染时都会重新加载
 func (en *Engine) LoadHTMLFiles(files ...string) {
-	templ := template.Must(
-		template.New("").
-			Delims(en.delims.Left, en.delims.Right).
-			Funcs(en.FuncMap).
-			ParseFiles(files...))
-	en.SetHTMLTemplate(templ)
+	templ := template.Must(en.newTemplate().ParseFiles(files...))
+	en.loadHTML(templ, render.HTMLDebug{Files: files})
+}
+
+// LoadHTMLFS 从fsys中加载匹配patterns的模板，可以配合embed.FS将模板打包进二进制文件
+func (en *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	templ := template.Must(en.newTemplate().ParseFS(fsys, patterns...))
+	en.loadHTML(templ, render.HTMLDebug{FileSystem: fsys, Patterns: patterns})
+}
+
+// SetHTMLRender 设置自定义的HTMLRender，如render.HTMLMulti
+// *render.HTMLMulti的Debug会根据当前是否为debug模式设置，debug模式下每次渲染时重新解析
+func (en *Engine) SetHTMLRender(r render.HTMLRender) {
+	if multi, ok := r.(*render.HTMLMulti); ok {
+		multi.Debug = IsDebugging()
+	}
+	en.HTMLRender = r
 }
 
 func (en *Engine) SetHTMLTemplate(templ *template.Template) {
-	en.HTMLRender = render.HTMLProduction{Template: templ.Funcs(en.FuncMap)}
+	if len(en.trees) > 0 {
+		debugPrintWARNINGSetHTMLTemplate()
+	}
+	en.HTMLRender = render.HTMLProduction{Template: templ.Funcs(en.FuncMap), Delims: en.delims}
+	en.htmlLoaded = true
 }
 
-func (en *Engine) SetFuncMap(funcMap template.FuncMap) {
-	en.FuncMap = funcMap
+// SecureJSONPrefix 设置Context.SecureJSON使用的前缀，默认为"while(1);"
+func (en *Engine) SecureJSONPrefix(prefix string) *Engine {
+	en.secureJSONPrefix = prefix
+	return en
 }
 
 func (en *Engine) NoRoute(handlers ...HandleFunc) {
@@ -101,15 +247,26 @@ func (en *Engine) NoRoute(handlers ...HandleFunc) {
 	en.rebuild404Handlers()
 }
 
+func (en *Engine) NoMethod(handlers ...HandleFunc) {
+	en.noMethod = handlers
+	en.rebuild405Handlers()
+}
+
 func (en *Engine) rebuild404Handlers() {
 	en.allNoRoute = en.combineHandlers(en.noRoute)
 }
 
+func (en *Engine) rebuild405Handlers() {
+	en.allNoMethod = en.combineHandlers(en.noMethod)
+}
+
 func (en *Engine) addRoute(method, path string, handlers []HandleFunc) {
 	assert1(path[0] == '/', "path must begin with '/'")
 	assert1(method != "", "HTTP method can not be empty")
 	assert1(len(handlers) > 0, "there must be at least one handler")
 
+	debugPrintRoute(method, path, handlers)
+
 	root := en.trees.get(method)
 	if root == nil {
 		root = new(node)
@@ -153,67 +310,302 @@ func iterate(path, method string, routes RoutesInfo, root *node) RoutesInfo {
 }
 
 func (en *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	params := make(Params, 0, en.maxParams)
-	skippedNodes := make([]skippedNode, 0, en.maxSections)
-	c := &Context{
-		engine:       en,
-		W:            w,
-		R:            r,
-		params:       &params,
-		skippedNodes: &skippedNodes,
-		index:        -1,
-	}
+	c := en.pool.Get().(*Context)
+	c.writermem.reset(w)
+	c.R = r
+	c.reset()
 
 	en.handleRequest(c)
+	c.writeBodyTooLarge()
+	c.writermem.WriteHeaderNow()
+
+	en.pool.Put(c)
 }
 
 func (en *Engine) handleRequest(c *Context) {
 	httpMethod := c.R.Method
 	rPath := c.R.URL.Path
 
-	t := en.trees
-	for i := 0; i < len(t); i++ {
-		if t[i].method != httpMethod {
-			continue
+	if limit := en.MaxRequestBodySize; limit > 0 && c.R.Body != nil {
+		if c.R.ContentLength > limit {
+			c.handlers = en.Handlers
+			serveError(c, http.StatusRequestEntityTooLarge, default413Body)
+			return
 		}
-		root := t[i].root
+		// Content-Length未知时在读取超出时返回*http.MaxBytesError，并记录到Context
+		// 传入原始的ResponseWriter，使http.Server在超出时关闭连接
+		c.R.Body = maxBytesBody{http.MaxBytesReader(c.writermem.ResponseWriter, c.R.Body, limit), c}
+	}
+
+	if en.serveRoute(c, httpMethod, rPath) {
+		return
+	}
 
-		value := root.getValue(rPath, c.params, c.skippedNodes, false)
-		if value.params != nil {
-			c.Params = *value.params
+	if httpMethod == http.MethodHead && en.HandleHEAD {
+		w := c.W
+		c.W = headResponseWriter{w}
+		if en.serveRoute(c, http.MethodGet, rPath) {
+			return
 		}
-		if value.handlers != nil {
-			c.handlers = value.handlers
-			c.fullPath = value.fullPath
-			log.Println(httpMethod, c.fullPath, len(c.handlers), "handler[s]")
-			c.Next()
+		c.W = w
+	}
+
+	if httpMethod == http.MethodOptions && en.HandleOPTIONS {
+		if allowed := en.allowedMethods(httpMethod, rPath, c.skippedNodes); len(allowed) > 0 {
+			c.W.Header().Set("Allow", strings.Join(allowed, ", "))
+			c.handlers = en.Handlers
+			serveError(c, http.StatusNoContent, nil)
 			return
 		}
-		if httpMethod != http.MethodConnect && rPath != "/" {
-			if value.tsr && en.RedirectTrailingSlash {
-				redirectTrailingSlash(c)
-				return
-			}
-			if en.RedirectFixedPath && redirectFixedPath(c, root, en.RedirectFixedPath) {
-				return
-			}
+	}
+
+	if en.HandleMethodNotAllowed {
+		if allowed := en.allowedMethods(httpMethod, rPath, c.skippedNodes); len(allowed) > 0 {
+			c.W.Header().Set("Allow", strings.Join(allowed, ", "))
+			c.handlers = en.allNoMethod
+			serveError(c, http.StatusMethodNotAllowed, default405Body)
+			return
 		}
-		break
 	}
 	c.handlers = en.allNoRoute
-	c.String(http.StatusNotFound, string(default404Body))
+	serveError(c, http.StatusNotFound, default404Body)
+}
+
+// serveRoute 在method对应的路由树中查找rPath并处理请求，
+// 包括尾部斜杠和大小写的重定向，请求被处理时返回true
+func (en *Engine) serveRoute(c *Context, method, rPath string) bool {
+	root := en.trees.get(method)
+	if root == nil {
+		return false
+	}
+
+	c.Params = c.Params[:0]
+	*c.params = (*c.params)[:0]
+	*c.skippedNodes = (*c.skippedNodes)[:0]
+	value := root.getValue(rPath, c.params, c.skippedNodes, false)
+	if value.params != nil {
+		c.Params = *value.params
+	}
+	if value.handlers != nil {
+		c.handlers = value.handlers
+		c.fullPath = value.fullPath
+		c.Next()
+		return true
+	}
+	if method != http.MethodConnect && rPath != "/" {
+		if value.tsr && en.RedirectTrailingSlash {
+			redirectTrailingSlash(c)
+			return true
+		}
+		if en.RedirectFixedPath && redirectFixedPath(c, root, en.RedirectFixedPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedMethods 返回除except之外，能够匹配rPath的所有方法
+func (en *Engine) allowedMethods(except, rPath string, skippedNodes *[]skippedNode) []string {
+	var allowed []string
+	hasGet, hasHead, hasOptions := false, false, false
+	for _, tree := range en.trees {
+		if tree.method == except {
+			continue
+		}
+		*skippedNodes = (*skippedNodes)[:0]
+		if value := tree.root.getValue(rPath, nil, skippedNodes, false); value.handlers != nil {
+			allowed = append(allowed, tree.method)
+			hasGet = hasGet || tree.method == http.MethodGet
+			hasHead = hasHead || tree.method == http.MethodHead
+			hasOptions = hasOptions || tree.method == http.MethodOptions
+		}
+	}
+	// HEAD可以回退到GET路由
+	if en.HandleHEAD && hasGet && !hasHead && except != http.MethodHead {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if en.HandleOPTIONS && len(allowed) > 0 && !hasOptions {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+// serveError 先执行NoRoute/NoMethod设置的handlers，都没有响应时再返回默认内容
+// handlers设置了其他状态码但没有写入响应体时，保留该状态码且不写入默认内容
+func serveError(c *Context, code int, defaultMessage []byte) {
+	c.Status(code)
+	c.Next()
+	if c.W.Written() {
+		return
+	}
+	if c.W.Status() != code || defaultMessage == nil {
+		c.W.WriteHeaderNow()
+		return
+	}
+	c.String(code, BytesToString(defaultMessage))
+}
+
+func (en *Engine) logger() *slog.Logger {
+	if en.Logger != nil {
+		return en.Logger
+	}
+	return slog.Default()
 }
 
 func (en *Engine) RoutesDebug() {
 	rs := en.Routes()
 	for _, r := range rs {
-		log.Println(r)
+		en.logger().Info("route",
+			slog.String("method", r.Method),
+			slog.String("path", r.Path),
+			slog.String("handler", r.Handler),
+			slog.Int("handlers", r.HandlerLen))
 	}
 }
 
-func (en *Engine) Run() {
-	log.Println("listening :8888")
-	http.ListenAndServe(":8888", en)
+// Run 监听addr并处理请求，addr为空时依次使用环境变量PORT和默认的":8888"
+func (en *Engine) Run(addr ...string) error {
+	address := resolveAddress(addr)
+	srv, err := en.newServer(address)
+	if err != nil {
+		return err
+	}
+	defer en.removeServer(srv)
+	en.logger().Info("listening", slog.String("addr", address))
+	return srv.ListenAndServe()
+}
+
+// RunTLS 以https方式监听addr
+func (en *Engine) RunTLS(addr, certFile, keyFile string) error {
+	srv, err := en.newServer(addr)
+	if err != nil {
+		return err
+	}
+	defer en.removeServer(srv)
+	en.logger().Info("listening", slog.String("addr", addr), slog.Bool("tls", true))
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// RunUnix 监听unix socket文件，已存在的socket文件会先删除，其他类型的文件返回错误
+func (en *Engine) RunUnix(file string) error {
+	srv, err := en.newServer(file)
+	if err != nil {
+		return err
+	}
+	defer en.removeServer(srv)
+	en.logger().Info("listening", slog.String("unix", file))
+	if fi, err := os.Lstat(file); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("rough: %s exists and is not a unix socket", file)
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	defer os.Remove(file)
+	return srv.Serve(listener)
+}
+
+// RunFd 监听已打开的文件描述符，常用于由systemd等进程传入的socket
+func (en *Engine) RunFd(fd int) error {
+	name := fmt.Sprintf("fd@%d", fd)
+	srv, err := en.newServer(name)
+	if err != nil {
+		return err
+	}
+	defer en.removeServer(srv)
+	en.logger().Info("listening", slog.Int("fd", fd))
+	f := os.NewFile(uintptr(fd), name)
+	// FileListener会复制文件描述符，原来的文件需要关闭
+	defer f.Close()
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	return srv.Serve(listener)
+}
+
+// RunListener 在已有的listener上处理请求
+func (en *Engine) RunListener(listener net.Listener) error {
+	srv, err := en.newServer(listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	defer en.removeServer(srv)
+	en.logger().Info("listening", slog.String("addr", listener.Addr().String()))
+	return srv.Serve(listener)
+}
+
+// Shutdown 停止所有正在运行的Run*接收新的连接，并等待处理中的请求完成或ctx结束
+// 常在收到SIGTERM后调用，调用后正在运行的Run*会返回http.ErrServerClosed
+// Shutdown执行期间开始的Run*直接返回http.ErrServerClosed，Shutdown返回之后仍然可以再次Run*
+func (en *Engine) Shutdown(ctx context.Context) error {
+	en.srvMu.Lock()
+	en.shuttingDown++
+	srvs := append([]*http.Server(nil), en.srvs...)
+	en.srvMu.Unlock()
+	defer func() {
+		en.srvMu.Lock()
+		en.shuttingDown--
+		en.srvMu.Unlock()
+	}()
+
+	errs := make([]error, len(srvs))
+	var wg sync.WaitGroup
+	for i, srv := range srvs {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}(i, srv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Servers 返回正在运行的Run*使用的http.Server，每次Run*都会创建新的http.Server
+func (en *Engine) Servers() []*http.Server {
+	en.srvMu.Lock()
+	defer en.srvMu.Unlock()
+	return append([]*http.Server(nil), en.srvs...)
+}
+
+// newServer 创建并记录Run*使用的http.Server，Shutdown执行期间返回http.ErrServerClosed
+func (en *Engine) newServer(addr string) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           en,
+		ReadTimeout:       en.ReadTimeout,
+		ReadHeaderTimeout: en.ReadHeaderTimeout,
+		WriteTimeout:      en.WriteTimeout,
+		IdleTimeout:       en.IdleTimeout,
+	}
+	en.srvMu.Lock()
+	defer en.srvMu.Unlock()
+	if en.shuttingDown > 0 {
+		return nil, http.ErrServerClosed
+	}
+	en.srvs = append(en.srvs, srv)
+	return srv, nil
+}
+
+func (en *Engine) removeServer(srv *http.Server) {
+	en.srvMu.Lock()
+	defer en.srvMu.Unlock()
+	for i, s := range en.srvs {
+		if s == srv {
+			en.srvs = append(en.srvs[:i], en.srvs[i+1:]...)
+			return
+		}
+	}
 }
 
 func redirectTrailingSlash(c *Context) {
@@ -253,6 +645,10 @@ func redirectRequest(c *Context) {
 	if req.Method != http.MethodGet {
 		code = http.StatusTemporaryRedirect
 	}
-	log.Printf("redirecting request %d: %s --> %s", code, rPath, rURL)
+	c.engine.logger().Debug("redirecting request",
+		slog.String("method", req.Method),
+		slog.Int("status", code),
+		slog.String("path", rPath),
+		slog.String("location", rURL))
 	c.Redirect(code, rURL)
 }
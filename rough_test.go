@@ -0,0 +1,448 @@
+package rough
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func init() {
+	SetMode(TestMode)
+}
+
+type mockWriter struct {
+	headers http.Header
+}
+
+func newMockWriter() *mockWriter {
+	return &mockWriter{http.Header{}}
+}
+
+func (m *mockWriter) Header() (h http.Header) {
+	return m.headers
+}
+
+func (m *mockWriter) Write(p []byte) (n int, err error) {
+	return len(p), nil
+}
+
+func (m *mockWriter) WriteString(s string) (n int, err error) {
+	return len(s), nil
+}
+
+func (m *mockWriter) WriteHeader(int) {}
+
+func runRequest(b *testing.B, en *Engine, method, path string) {
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		panic(err)
+	}
+	w := newMockWriter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		en.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkTreeStatic(b *testing.B) {
+	en := New()
+	en.GET("/ping", func(c *Context) {})
+	root := en.trees.get(http.MethodGet)
+	c := en.allocateContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.reset()
+		root.getValue("/ping", c.params, c.skippedNodes, false)
+	}
+}
+
+func BenchmarkTreeParam(b *testing.B) {
+	en := New()
+	en.GET("/user/:name/files/*filepath", func(c *Context) {})
+	root := en.trees.get(http.MethodGet)
+	c := en.allocateContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.reset()
+		root.getValue("/user/rough/files/a/b.txt", c.params, c.skippedNodes, false)
+	}
+}
+
+func BenchmarkServeStatic(b *testing.B) {
+	en := New()
+	en.GET("/ping", func(c *Context) {})
+	runRequest(b, en, http.MethodGet, "/ping")
+}
+
+func BenchmarkServeParam(b *testing.B) {
+	en := New()
+	en.GET("/user/:name/files/*filepath", func(c *Context) {})
+	runRequest(b, en, http.MethodGet, "/user/rough/files/a/b.txt")
+}
+
+func TestContextReuse(t *testing.T) {
+	en := New()
+	en.GET("/set/:id", func(c *Context) {
+		if _, ok := c.Get("id"); ok {
+			t.Error("keys leaked from previous request")
+		}
+		c.Set("id", c.Param("id"))
+		c.String(http.StatusOK, c.Param("id"))
+	})
+	for _, id := range []string{"1", "2", "3"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/set/"+id, nil)
+		en.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != id {
+			t.Errorf("got %d %q, want 200 %q", w.Code, w.Body.String(), id)
+		}
+	}
+}
+
+func TestRouteAddedAfterFirstRequest(t *testing.T) {
+	en := New()
+	en.GET("/a/:x", func(c *Context) {})
+	en.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a/1", nil))
+
+	// 池中的Context是按照只有一个参数分配的
+	en.GET("/b/:x/:y/:z", func(c *Context) {
+		c.String(http.StatusOK, c.Param("x")+c.Param("y")+c.Param("z"))
+	})
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/b/1/2/3", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "123" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	en := New()
+	en.HandleMethodNotAllowed = true
+	en.GET("/path", func(c *Context) {})
+	en.PUT("/path", func(c *Context) {})
+	en.POST("/other", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/path", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, PUT, HEAD" {
+		t.Errorf("Allow = %q, want %q", allow, "GET, PUT, HEAD")
+	}
+
+	en.NoMethod(func(c *Context) {
+		c.String(http.StatusTeapot, "no method")
+	})
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/path", nil))
+	if w.Code != http.StatusTeapot || w.Body.String() != "no method" {
+		t.Errorf("got %d %q, want NoMethod handler response", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 page not found" {
+		t.Errorf("got %d %q, want default 404", w.Code, w.Body.String())
+	}
+
+	// 只设置状态码时保留该状态码，不写入默认内容
+	en.NoRoute(func(c *Context) {
+		c.Status(http.StatusTeapot)
+	})
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusTeapot || w.Body.Len() != 0 {
+		t.Errorf("got %d %q, want 418 with empty body", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleOPTIONS(t *testing.T) {
+	en := New()
+	en.HandleOPTIONS = true
+	en.GET("/path", func(c *Context) {})
+	en.POST("/path", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/path", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST, HEAD, OPTIONS" {
+		t.Errorf("Allow = %q, want %q", allow, "GET, POST, HEAD, OPTIONS")
+	}
+
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestHeadFallbackToGet(t *testing.T) {
+	en := New()
+	en.GET("/path", func(c *Context) {
+		c.String(http.StatusOK, "body")
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/path", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", w.Body.String())
+	}
+
+	en.HandleHEAD = false
+	w = httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/path", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	en := New()
+	en.Use(func(c *Context) {
+		c.Next()
+		if !c.W.Written() || c.W.Status() != http.StatusCreated || c.W.Size() != 5 {
+			t.Errorf("written=%v status=%d size=%d", c.W.Written(), c.W.Status(), c.W.Size())
+		}
+	})
+	en.GET("/", func(c *Context) {
+		c.String(http.StatusCreated, "hello")
+		c.String(http.StatusOK, "again")
+	})
+
+	w := httptest.NewRecorder()
+	en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "hello" {
+		t.Errorf("got %d %q, want 201 %q", w.Code, w.Body.String(), "hello")
+	}
+}
+
+func TestLoadHTMLFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/index.html": {Data: []byte(`[[define "index"]]hello [[upper .]][[end]]`)},
+		"templates/raw.html":   {Data: []byte(`{{.}}`)},
+	}
+
+	buf := new(bytes.Buffer)
+	en := New()
+	en.Logger = slog.New(slog.NewTextHandler(buf, nil))
+	en.Delims("[[", "]]").SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	en.LoadHTMLFS(fsys, "templates/*.html")
+	en.GET("/:name", func(c *Context) {
+		c.HTML(http.StatusOK, c.Param("name"), "rough")
+	})
+
+	cases := map[string]string{
+		"/index":    "hello ROUGH",
+		"/raw.html": "{{.}}",
+	}
+	for path, want := range cases {
+		w := httptest.NewRecorder()
+		en.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != want {
+			t.Errorf("%s: body = %q, want %q", path, w.Body.String(), want)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+
+	en.SetFuncMap(template.FuncMap{})
+	if !strings.Contains(buf.String(), "SetFuncMap called after templates were loaded") {
+		t.Errorf("missing warning, log = %q", buf.String())
+	}
+}
+
+func TestResolveAddress(t *testing.T) {
+	t.Setenv("PORT", "")
+	if got := resolveAddress(nil); got != defaultAddress {
+		t.Errorf("default = %q", got)
+	}
+	t.Setenv("PORT", "9999")
+	if got := resolveAddress(nil); got != ":9999" {
+		t.Errorf("PORT = %q", got)
+	}
+	if got := resolveAddress([]string{":7777"}); got != ":7777" {
+		t.Errorf("explicit = %q", got)
+	}
+}
+
+// waitServers 等待n个Run*启动
+func waitServers(t *testing.T, en *Engine, n int) {
+	t.Helper()
+	for i := 0; len(en.Servers()) != n; i++ {
+		if i > 200 {
+			t.Fatalf("servers = %d, want %d", len(en.Servers()), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestShutdownDrainsAllServers(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	en := New()
+	en.GET("/slow", func(c *Context) {
+		close(entered)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	run := func() (string, chan error) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() { done <- en.RunListener(ln) }()
+		return "http://" + ln.Addr().String(), done
+	}
+	url1, done1 := run()
+	_, done2 := run()
+	runDone := make(chan error, 1)
+	go func() { runDone <- en.Run("127.0.0.1:0") }()
+	waitServers(t, en, 3)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url1 + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		resCh <- result{string(b), err}
+	}()
+	<-entered
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- en.Shutdown(context.Background()) }()
+	select {
+	case err := <-shutdownDone:
+		t.Fatalf("Shutdown returned before in-flight request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Shutdown执行期间开始的Run*不会再处理请求
+	lateLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lateLn.Close()
+	if err := en.RunListener(lateLn); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Run* during Shutdown = %v, want ErrServerClosed", err)
+	}
+	close(release)
+
+	if res := <-resCh; res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v", res.body, res.err)
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	for _, done := range []chan error{done1, done2, runDone} {
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Run* = %v, want ErrServerClosed", err)
+		}
+	}
+	if n := len(en.Servers()); n != 0 {
+		t.Errorf("servers after shutdown = %d", n)
+	}
+
+	// Shutdown之后可以再次Run*
+	url3, done3 := run()
+	waitServers(t, en, 1)
+	resp, err := http.Get(url3 + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("rerun status = %d", resp.StatusCode)
+	}
+	if err := en.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done3; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("rerun = %v", err)
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	dir := t.TempDir()
+
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().RunUnix(regular); err == nil {
+		t.Error("RunUnix should refuse a regular file")
+	}
+	if b, err := os.ReadFile(regular); err != nil || string(b) != "keep" {
+		t.Errorf("regular file was modified: %q, %v", b, err)
+	}
+
+	sock := filepath.Join(dir, "rough.sock")
+	// 残留的socket文件会被删除
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	en := New()
+	en.GET("/", func(c *Context) { c.String(http.StatusOK, "unix") })
+	done := make(chan error, 1)
+	go func() { done <- en.RunUnix(sock) }()
+	waitServers(t, en, 1)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	var body []byte
+	for i := 0; i < 100; i++ {
+		resp, err := client.Get("http://unix/")
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if string(body) != "unix" {
+		t.Errorf("body = %q", body)
+	}
+	if err := en.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("RunUnix = %v", err)
+	}
+}
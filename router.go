@@ -1,7 +1,6 @@
 package rough
 
 import (
-	"log"
 	"net/http"
 	"path"
 	"regexp"
@@ -56,7 +55,6 @@ func (group *RouterGroup) Static(relativePath, root string) {
 
 	handler := func(c *Context) {
 		file := c.Param("filepath")
-		log.Println(file)
 		f, err := fs.Open(file)
 		if err != nil {
 			c.W.WriteHeader(http.StatusNotFound)
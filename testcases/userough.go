@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/cainmusic/rough"
 )
@@ -33,7 +39,21 @@ func main() {
 
 	r.RoutesDebug()
 
-	r.Run() // :8888
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.Shutdown(ctx); err != nil {
+			log.Println("shutdown:", err)
+		}
+	}()
+
+	if err := r.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) { // :8888
+		log.Fatal(err)
+	}
 }
 
 func quickResponseUrlString(c *rough.Context) {
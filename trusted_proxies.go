@@ -0,0 +1,96 @@
+package rough
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	// PlatformGoogleAppEngine 使用Google App Engine设置的请求头获取客户端IP
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	// PlatformCloudflare 使用Cloudflare设置的请求头获取客户端IP
+	PlatformCloudflare = "CF-Connecting-IP"
+	// PlatformFlyIO 使用Fly.io设置的请求头获取客户端IP
+	PlatformFlyIO = "Fly-Client-IP"
+)
+
+// SetTrustedProxies 设置可信的代理，可以是IP或者CIDR
+// 只有来自可信代理的请求，ClientIP才会读取RemoteIPHeaders中的请求头
+// 传入nil表示不信任任何代理，这也是默认的行为
+func (en *Engine) SetTrustedProxies(trustedProxies []string) error {
+	cidrs, err := prepareTrustedCIDRs(trustedProxies)
+	if err != nil {
+		return err
+	}
+	en.trustedCIDRs = cidrs
+	return nil
+}
+
+func prepareTrustedCIDRs(trustedProxies []string) ([]*net.IPNet, error) {
+	if trustedProxies == nil {
+		return nil, nil
+	}
+
+	cidrs := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, trustedProxy := range trustedProxies {
+		if !strings.Contains(trustedProxy, "/") {
+			ip := parseIP(trustedProxy)
+			if ip == nil {
+				return cidrs, &net.ParseError{Type: "IP address", Text: trustedProxy}
+			}
+
+			switch len(ip) {
+			case net.IPv4len:
+				trustedProxy += "/32"
+			case net.IPv6len:
+				trustedProxy += "/128"
+			}
+		}
+		_, cidrNet, err := net.ParseCIDR(trustedProxy)
+		if err != nil {
+			return cidrs, err
+		}
+		cidrs = append(cidrs, cidrNet)
+	}
+	return cidrs, nil
+}
+
+// isTrustedProxy 判断ip是否在可信代理中
+func (en *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range en.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHeader 从右向左解析X-Forwarded-For形式的请求头，返回第一个不可信的IP
+func (en *Engine) validateHeader(header string) (clientIP string, valid bool) {
+	if header == "" {
+		return "", false
+	}
+	items := strings.Split(header, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		ipStr := strings.TrimSpace(items[i])
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			break
+		}
+
+		// X-Forwarded-For由客户端IP和各级代理IP组成，最左边是客户端
+		if i == 0 || !en.isTrustedProxy(ip) {
+			return ipStr, true
+		}
+	}
+	return "", false
+}
+
+// parseIP 解析IP，IPv4返回4字节的形式
+func parseIP(ip string) net.IP {
+	parsedIP := net.ParseIP(ip)
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		return ipv4
+	}
+	return parsedIP
+}
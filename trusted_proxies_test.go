@@ -0,0 +1,77 @@
+package rough
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	en := New()
+	c := en.allocateContext()
+	c.R = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.R.RemoteAddr = "  10.0.0.1:1234 "
+	c.R.Header.Set("X-Forwarded-For", "20.20.20.20, 30.30.30.30, 10.0.0.2")
+	c.R.Header.Set("X-Real-IP", "40.40.40.40")
+	c.R.Header.Set(PlatformCloudflare, "50.50.50.50")
+
+	// 默认不信任任何代理
+	if ip := c.ClientIP(); ip != "10.0.0.1" {
+		t.Errorf("ClientIP() = %q, want remote addr", ip)
+	}
+
+	if err := en.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	if ip := c.ClientIP(); ip != "30.30.30.30" {
+		t.Errorf("ClientIP() = %q, want first untrusted hop", ip)
+	}
+
+	en.RemoteIPHeaders = []string{"X-Real-IP"}
+	if ip := c.ClientIP(); ip != "40.40.40.40" {
+		t.Errorf("ClientIP() = %q, want X-Real-IP", ip)
+	}
+
+	en.ForwardedByClientIP = false
+	if ip := c.ClientIP(); ip != "10.0.0.1" {
+		t.Errorf("ClientIP() = %q, want remote addr", ip)
+	}
+
+	en.TrustedPlatform = PlatformCloudflare
+	if ip := c.ClientIP(); ip != "50.50.50.50" {
+		t.Errorf("ClientIP() = %q, want platform header", ip)
+	}
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	en := New()
+	if err := en.SetTrustedProxies([]string{"192.168.1.1", "::1", "172.16.0.0/12"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(en.trustedCIDRs) != 3 {
+		t.Errorf("trustedCIDRs = %v", en.trustedCIDRs)
+	}
+	if err := en.SetTrustedProxies([]string{"not an ip"}); err == nil {
+		t.Error("expected error for invalid proxy")
+	}
+}
+
+func TestContextCookie(t *testing.T) {
+	en := New()
+	en.GET("/", func(c *Context) {
+		if v, err := c.Cookie("user"); err != nil || v != "rough go" {
+			t.Errorf("Cookie() = %q, %v", v, err)
+		}
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie("user", "rough go", 60, "", "", true, true)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "user", Value: "rough+go"})
+	en.ServeHTTP(w, req)
+	want := "user=rough+go; Path=/; Max-Age=60; HttpOnly; Secure; SameSite=Lax"
+	if got := w.Header().Get("Set-Cookie"); got != want {
+		t.Errorf("Set-Cookie = %q, want %q", got, want)
+	}
+}
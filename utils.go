@@ -1,9 +1,14 @@
 package rough
 
 import (
+	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
+	"unicode"
 	"unsafe"
 )
 
@@ -54,3 +59,72 @@ func assert1(guard bool, text string) {
 func nameOfFunction(f any) string {
 	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 }
+
+func resolveAddress(addr []string) string {
+	switch len(addr) {
+	case 0:
+		if port := os.Getenv("PORT"); port != "" {
+			return ":" + port
+		}
+		return defaultAddress
+	case 1:
+		return addr[0]
+	default:
+		panic("too many parameters")
+	}
+}
+
+// filterFlags 去掉Content-Type中的参数，如"; charset=utf-8"
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// containsDotDot 判断路径中是否有".."元素
+func containsDotDot(v string) bool {
+	if !strings.Contains(v, "..") {
+		return false
+	}
+	for _, ent := range strings.FieldsFunc(filepath.ToSlash(v), isSlashRune) {
+		if ent == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func isSlashRune(r rune) bool { return r == '/' || r == '\\' }
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// asciiFallback 将s中的非ASCII字符替换为"_"，用于不支持filename*的客户端
+func asciiFallback(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || r < ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// encodeRFC5987 按照RFC 5987对s进行百分号编码，只保留字母、数字和"-_.~"
+func encodeRFC5987(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
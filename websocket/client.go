@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBadHandshake 表示服务端的握手响应不合法
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Dialer 用于建立客户端连接，主要用于测试和服务之间的调用
+type Dialer struct {
+	// HandshakeTimeout 是建立连接和完成握手的超时时间，0表示不限制
+	HandshakeTimeout time.Duration
+
+	// FrameSize 是写入时单个帧的最大payload，默认4096
+	FrameSize int
+
+	// Subprotocols 是请求的子协议
+	Subprotocols []string
+
+	// EnableCompression 为true时提议使用permessage-deflate
+	EnableCompression bool
+
+	// TLSClientConfig 用于wss连接
+	TLSClientConfig *tls.Config
+}
+
+// DefaultDialer 是Dial使用的Dialer
+var DefaultDialer = &Dialer{HandshakeTimeout: 45 * time.Second}
+
+// Dial 使用DefaultDialer连接urlStr，支持ws、wss以及http、https
+func Dial(urlStr string, header http.Header) (*Conn, *http.Response, error) {
+	return DefaultDialer.DialContext(context.Background(), urlStr, header)
+}
+
+// DialContext 连接urlStr并完成握手，握手失败时返回服务端的响应
+func (d *Dialer) DialContext(ctx context.Context, urlStr string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme = "https"
+	default:
+		return nil, nil, errors.New("websocket: bad scheme " + u.Scheme)
+	}
+
+	if d.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.HandshakeTimeout)
+		defer cancel()
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if d.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var nd net.Dialer
+	netConn, err := nd.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme == "https" {
+		cfg := d.TLSClientConfig.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(netConn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, nil, err
+		}
+		netConn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != computeAcceptKey(key) {
+		netConn.Close()
+		return nil, resp, ErrBadHandshake
+	}
+
+	compress := false
+	for _, ext := range parseExtensions(resp.Header.Values("Sec-WebSocket-Extensions")) {
+		if ext.name != "permessage-deflate" {
+			continue
+		}
+		// 每条消息独立解压，要求服务端不保留压缩上下文
+		if _, ok := ext.params["server_no_context_takeover"]; !d.EnableCompression || !ok {
+			netConn.Close()
+			return nil, resp, ErrBadHandshake
+		}
+		compress = true
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	c := newConn(netConn, br, false, d.FrameSize)
+	c.subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	c.compress = compress
+	c.writeCompress = compress
+	return c, resp, nil
+}
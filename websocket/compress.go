@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+)
+
+// 每条消息独立压缩（no_context_takeover），压缩后去掉结尾的空stored块
+const deflateTail = "\x00\x00\xff\xff"
+
+func compressMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte(deflateTail)), nil
+}
+
+func decompressMessage(data []byte, limit int64) ([]byte, error) {
+	// 补上去掉的结尾以及一个final块，使flate能够正常读到EOF
+	r := io.MultiReader(bytes.NewReader(data), strings.NewReader(deflateTail+"\x01\x00\x00\xff\xff"))
+	fr := flate.NewReader(r)
+	defer fr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(fr, limit+1))
+	if err != nil {
+		return nil, &protocolError{CloseInvalidFramePayloadData, "invalid compressed data"}
+	}
+	if int64(len(out)) > limit {
+		return nil, &protocolError{CloseMessageTooBig, ErrReadLimit.Error()}
+	}
+	return out, nil
+}
+
+// extension 是Sec-WebSocket-Extensions中的一项
+type extension struct {
+	name   string
+	params map[string]string
+}
+
+func parseExtensions(values []string) []extension {
+	var exts []extension
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			parts := strings.Split(item, ";")
+			name := strings.ToLower(strings.TrimSpace(parts[0]))
+			if name == "" {
+				continue
+			}
+			ext := extension{name: name, params: map[string]string{}}
+			for _, p := range parts[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				ext.params[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+			}
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+const deflateResponse = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// acceptDeflate 判断客户端的permessage-deflate提议是否可以接受
+// flate包固定使用32K的窗口，不能满足更小的server_max_window_bits
+func acceptDeflate(exts []extension) bool {
+	for _, ext := range exts {
+		if ext.name != "permessage-deflate" {
+			continue
+		}
+		ok := true
+		for k, v := range ext.params {
+			switch k {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				ok = v == "15"
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,559 @@
+// Package websocket 是只依赖标准库的RFC 6455 WebSocket实现
+// 支持分片消息、ping/pong、关闭握手以及permessage-deflate压缩（RFC 7692，不保留上下文）
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，对应帧的opcode
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭码，见RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseTLSHandshake            = 1015
+)
+
+const (
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	rsv2Bit  = 1 << 5
+	rsv3Bit  = 1 << 4
+	maskBit  = 1 << 7
+
+	maxControlPayload = 125
+
+	defaultFrameSize = 4096
+	defaultReadLimit = 32 << 20
+)
+
+var (
+	// ErrCloseSent 表示已经发送了关闭帧，不能再写入数据消息
+	ErrCloseSent = errors.New("websocket: close sent")
+	// ErrReadLimit 表示消息超出了SetReadLimit设置的大小
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+// CloseError 表示收到了对端的关闭帧
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// IsCloseError 判断err是否是codes中任意一个关闭码对应的CloseError
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage 生成关闭帧的payload，code为CloseNoStatusReceived时返回空payload
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// Conn 是一个WebSocket连接
+// 同一时间只能有一个goroutine读，写操作可以在多个goroutine中并发调用
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	subprotocol   string
+	compress      bool
+	writeCompress bool
+	frameSize     int
+
+	wmu       sync.Mutex
+	bw        *bufio.Writer
+	closeSent bool
+
+	readLimit    int64
+	readErr      error
+	pingHandler  func(appData string) error
+	pongHandler  func(appData string) error
+	closeHandler func(code int, text string) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, frameSize int) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	if frameSize <= 0 {
+		frameSize = defaultFrameSize
+	}
+	c := &Conn{
+		conn:      conn,
+		br:        br,
+		bw:        bufio.NewWriterSize(conn, frameSize+14),
+		isServer:  isServer,
+		frameSize: frameSize,
+		readLimit: defaultReadLimit,
+	}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol 返回握手时协商的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed 返回是否协商了permessage-deflate
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// EnableWriteCompression 在协商了压缩时控制之后写入的消息是否压缩，默认压缩
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.wmu.Lock()
+	c.writeCompress = c.compress && enable
+	c.wmu.Unlock()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// NetConn 返回底层的网络连接
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit 设置单条消息（解压后）的最大字节数，超出时以CloseMessageTooBig关闭连接
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler 设置收到ping时的处理函数，为nil时回复相同数据的pong
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(appData string) error {
+			err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.pingHandler = h
+}
+
+// SetPongHandler 设置收到pong时的处理函数，为nil时忽略pong
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+// SetCloseHandler 设置收到关闭帧时的处理函数，为nil时回复相同关闭码的关闭帧
+// 处理函数返回之后ReadMessage返回*CloseError
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			err := c.WriteControl(CloseMessage, FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.closeHandler = h
+}
+
+// Close 直接关闭底层连接，不发送关闭帧
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// WriteClose 发送关闭帧开始关闭握手，之后应继续ReadMessage直到收到对端的关闭帧
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Time{})
+}
+
+// WriteControl 写入一个控制帧，deadline为零值时不设置写超时
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: bad control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if !deadline.IsZero() {
+		_ = c.conn.SetWriteDeadline(deadline)
+		defer c.conn.SetWriteDeadline(time.Time{})
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	if err := c.writeFrame(true, false, messageType, data); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// WriteMessage 写入一条数据消息，超过帧大小的消息会拆分为多个分片
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		if messageType == CloseMessage || messageType == PingMessage || messageType == PongMessage {
+			return c.WriteControl(messageType, data, time.Time{})
+		}
+		return fmt.Errorf("websocket: bad message type %d", messageType)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	compressed := false
+	if c.writeCompress {
+		var err error
+		if data, err = compressMessage(data); err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	opcode := messageType
+	for {
+		n := len(data)
+		if n > c.frameSize {
+			n = c.frameSize
+		}
+		fin := n == len(data)
+		if err := c.writeFrame(fin, compressed, opcode, data[:n]); err != nil {
+			return err
+		}
+		if fin {
+			break
+		}
+		data = data[n:]
+		opcode = continuationFrame
+		// 只有第一个分片设置RSV1
+		compressed = false
+	}
+	return c.bw.Flush()
+}
+
+func (c *Conn) writeFrame(fin, rsv1 bool, opcode int, payload []byte) error {
+	var header [14]byte
+	header[0] = byte(opcode)
+	if fin {
+		header[0] |= finalBit
+	}
+	if rsv1 {
+		header[0] |= rsv1Bit
+	}
+
+	n := 2
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n += 8
+	}
+
+	if !c.isServer {
+		// 客户端发送的帧必须加掩码
+		header[1] |= maskBit
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		copy(header[n:], key[:])
+		n += 4
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(key, masked)
+		payload = masked
+	}
+
+	if _, err := c.bw.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := c.bw.Write(payload)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// protocolError 表示对端违反了协议，读取时会以Code关闭连接
+type protocolError struct {
+	Code int
+	Msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.Msg
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame 读取一个帧，remain是当前消息还可以读取的字节数
+func (c *Conn) readFrame(remain int64) (frame, error) {
+	var f frame
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return f, err
+	}
+
+	f.fin = header[0]&finalBit != 0
+	f.rsv1 = header[0]&rsv1Bit != 0
+	f.opcode = int(header[0] & 0xf)
+	if header[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return f, &protocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+
+	masked := header[1]&maskBit != 0
+	if masked != c.isServer {
+		if c.isServer {
+			return f, &protocolError{CloseProtocolError, "client frame is not masked"}
+		}
+		return f, &protocolError{CloseProtocolError, "server frame is masked"}
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		if ext[0]&0x80 != 0 {
+			return f, &protocolError{CloseProtocolError, "invalid frame length"}
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	switch f.opcode {
+	case CloseMessage, PingMessage, PongMessage:
+		if !f.fin {
+			return f, &protocolError{CloseProtocolError, "fragmented control frame"}
+		}
+		if f.rsv1 {
+			return f, &protocolError{CloseProtocolError, "compressed control frame"}
+		}
+		if length > maxControlPayload {
+			return f, &protocolError{CloseProtocolError, "control frame payload too large"}
+		}
+	case continuationFrame, TextMessage, BinaryMessage:
+		if length > remain {
+			return f, &protocolError{CloseMessageTooBig, ErrReadLimit.Error()}
+		}
+	default:
+		return f, &protocolError{CloseProtocolError, "unknown opcode " + strconv.Itoa(f.opcode)}
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return f, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, err
+	}
+	if masked {
+		maskBytes(key, f.payload)
+	}
+	return f, nil
+}
+
+// ReadMessage 读取下一条完整的数据消息，期间收到的控制帧交给对应的处理函数
+// 收到关闭帧时返回*CloseError，出错后之后的调用都会返回同样的错误
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		var pe *protocolError
+		if errors.As(err, &pe) {
+			_ = c.WriteControl(CloseMessage, FormatCloseMessage(pe.Code, pe.Msg), time.Now().Add(time.Second))
+		}
+		c.readErr = err
+	}
+	return messageType, p, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	compressed := false
+	var data []byte
+
+	for {
+		f, err := c.readFrame(c.readLimit - int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(f.payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(f.payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "unexpected continuation frame"}
+			}
+			if f.rsv1 {
+				return 0, nil, &protocolError{CloseProtocolError, "RSV1 set on continuation frame"}
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "expected continuation frame"}
+			}
+			if f.rsv1 && !c.compress {
+				return 0, nil, &protocolError{CloseProtocolError, "RSV1 set without compression"}
+			}
+			messageType = f.opcode
+			compressed = f.rsv1
+		}
+
+		data = append(data, f.payload...)
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if data, err = decompressMessage(data, c.readLimit); err != nil {
+				return 0, nil, err
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(data) {
+			return 0, nil, &protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in text message"}
+		}
+		return messageType, data, nil
+	}
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	code := CloseNoStatusReceived
+	text := ""
+	switch {
+	case len(payload) == 1:
+		return &protocolError{CloseProtocolError, "invalid close payload"}
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return &protocolError{CloseProtocolError, "invalid close code " + strconv.Itoa(code)}
+		}
+		if !utf8.ValidString(text) {
+			return &protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in close reason"}
+		}
+	}
+	if err := c.closeHandler(code, text); err != nil {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+func validCloseCode(code int) bool {
+	switch code {
+	case CloseNormalClosure, CloseGoingAway, CloseProtocolError, CloseUnsupportedData,
+		CloseInvalidFramePayloadData, ClosePolicyViolation, CloseMessageTooBig,
+		CloseMandatoryExtension, CloseInternalServerErr:
+		return true
+	}
+	return code >= 3000 && code <= 4999
+}
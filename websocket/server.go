@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// handshakeHeaders 由Upgrade自己写入或者不适用于101响应，responseHeader中的同名项会被忽略
+var handshakeHeaders = map[string]struct{}{
+	"Upgrade":                  {},
+	"Connection":               {},
+	"Sec-Websocket-Accept":     {},
+	"Sec-Websocket-Protocol":   {},
+	"Sec-Websocket-Extensions": {},
+	"Content-Length":           {},
+	"Transfer-Encoding":        {},
+}
+
+// HandshakeError 表示握手请求不合法
+type HandshakeError struct {
+	Status int
+	Reason string
+}
+
+func (e HandshakeError) Error() string {
+	return "websocket: " + e.Reason
+}
+
+// Upgrader 将HTTP请求升级为WebSocket连接，零值可以直接使用
+type Upgrader struct {
+	// HandshakeTimeout 是写出握手响应的超时时间，0表示不限制
+	HandshakeTimeout time.Duration
+
+	// FrameSize 是写入时单个帧的最大payload，超出的消息会被分片，默认4096
+	FrameSize int
+
+	// Subprotocols 是服务端支持的子协议，按照优先级排列
+	Subprotocols []string
+
+	// CheckOrigin 检查请求的Origin，为nil时只允许没有Origin或者Origin与Host相同的请求
+	CheckOrigin func(r *http.Request) bool
+
+	// EnableCompression 为true时接受客户端的permessage-deflate提议
+	EnableCompression bool
+
+	// Error 用于输出握手失败的响应，为nil时使用http.Error
+	Error func(w http.ResponseWriter, r *http.Request, status int, reason error)
+}
+
+func (u *Upgrader) returnError(w http.ResponseWriter, r *http.Request, status int, reason string) error {
+	err := HandshakeError{Status: status, Reason: reason}
+	if u.Error != nil {
+		u.Error(w, r, status, err)
+	} else {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, http.StatusText(status), status)
+	}
+	return err
+}
+
+// Upgrade 校验握手请求并接管连接，responseHeader会附加到101响应中
+// 失败时已经写出了错误响应，调用方不需要再写入
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, u.returnError(w, r, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, u.returnError(w, r, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.returnError(w, r, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, u.returnError(w, r, http.StatusUpgradeRequired, "unsupported version")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.returnError(w, r, http.StatusForbidden, "request origin not allowed")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.returnError(w, r, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, u.returnError(w, r, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && acceptDeflate(parseExtensions(r.Header.Values("Sec-WebSocket-Extensions")))
+
+	netConn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// http.Server的读写超时不应该作用于升级后的连接
+	_ = netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(computeAcceptKey(key))
+	b.WriteString("\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: " + deflateResponse + "\r\n")
+	}
+	for k, vs := range responseHeader {
+		if _, skip := handshakeHeaders[http.CanonicalHeaderKey(k)]; skip {
+			continue
+		}
+		for _, v := range vs {
+			b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Time{})
+	}
+
+	c := newConn(netConn, brw.Reader, true, u.FrameSize)
+	c.subprotocol = subprotocol
+	c.compress = compress
+	c.writeCompress = compress
+	return c, nil
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	clientProtocols := Subprotocols(r)
+	for _, sp := range u.Subprotocols {
+		for _, cp := range clientProtocols {
+			if sp == cp {
+				return sp
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols 返回客户端在Sec-WebSocket-Protocol中请求的子协议
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// IsWebSocketUpgrade 判断请求是否是WebSocket握手请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
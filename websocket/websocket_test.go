@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEchoServer(t *testing.T, u Upgrader) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r, http.Header{"X-Echo": {"1"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(1024)
+		for {
+			mt, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, p); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	url := newEchoServer(t, Upgrader{Subprotocols: []string{"chat"}, FrameSize: 16})
+
+	d := &Dialer{Subprotocols: []string{"other", "chat"}, FrameSize: 7}
+	conn, resp, err := d.DialContext(context.Background(), url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "chat" || resp.Header.Get("X-Echo") != "1" {
+		t.Errorf("subprotocol = %q, header = %v", conn.Subprotocol(), resp.Header)
+	}
+
+	messages := []struct {
+		mt   int
+		data []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, []byte{0, 1, 2, 255}},
+		// 两端都会拆分为多个分片
+		{TextMessage, []byte(strings.Repeat("分片", 50))},
+		{TextMessage, []byte{}},
+	}
+	for _, m := range messages {
+		if err := conn.WriteMessage(m.mt, m.data); err != nil {
+			t.Fatal(err)
+		}
+		mt, p, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mt != m.mt || !bytes.Equal(p, m.data) {
+			t.Errorf("got (%d, %q), want (%d, %q)", mt, p, m.mt, m.data)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	url := newEchoServer(t, Upgrader{EnableCompression: true})
+
+	conn, _, err := (&Dialer{EnableCompression: true, FrameSize: 5}).DialContext(context.Background(), url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if !conn.Compressed() {
+		t.Fatal("permessage-deflate not negotiated")
+	}
+
+	for _, msg := range []string{"", "hello", strings.Repeat("compress me ", 60)} {
+		if err := conn.WriteMessage(TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != msg {
+			t.Errorf("got %q, want %q", p, msg)
+		}
+	}
+
+	conn.EnableWriteCompression(false)
+	if err := conn.WriteMessage(TextMessage, []byte("plain")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "plain" {
+		t.Errorf("got %q, %v", p, err)
+	}
+}
+
+func TestPingPongAndClose(t *testing.T) {
+	url := newEchoServer(t, Upgrader{})
+	conn, _, err := Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pong := ""
+	conn.SetPongHandler(func(appData string) error {
+		pong = appData
+		return nil
+	})
+	if err := conn.WriteControl(PingMessage, []byte("ping"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("after ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "after ping" {
+		t.Fatalf("got %q, %v", p, err)
+	}
+	if pong != "ping" {
+		t.Errorf("pong = %q", pong)
+	}
+
+	if err := conn.WriteClose(CloseGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("x")); !errors.Is(err, ErrCloseSent) {
+		t.Errorf("write after close = %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !IsCloseError(err, CloseGoingAway) {
+		t.Errorf("err = %v, want close %d", err, CloseGoingAway)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	url := newEchoServer(t, Upgrader{})
+
+	cases := []struct {
+		name string
+		send func(c *Conn) error
+		code int
+	}{
+		{"unmasked frame", func(c *Conn) error {
+			c.isServer = true
+			defer func() { c.isServer = false }()
+			return c.WriteMessage(TextMessage, []byte("hi"))
+		}, CloseProtocolError},
+		{"invalid utf8", func(c *Conn) error {
+			return c.WriteMessage(TextMessage, []byte{0xff, 0xfe})
+		}, CloseInvalidFramePayloadData},
+		{"too big", func(c *Conn) error {
+			return c.WriteMessage(BinaryMessage, make([]byte, 2048))
+		}, CloseMessageTooBig},
+		{"unexpected continuation", func(c *Conn) error {
+			c.wmu.Lock()
+			defer c.wmu.Unlock()
+			if err := c.writeFrame(true, false, continuationFrame, []byte("x")); err != nil {
+				return err
+			}
+			return c.bw.Flush()
+		}, CloseProtocolError},
+		{"invalid close code", func(c *Conn) error {
+			return c.WriteControl(CloseMessage, []byte{0x03, 0xed}, time.Time{})
+		}, CloseProtocolError},
+	}
+	for _, tc := range cases {
+		conn, _, err := Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tc.send(conn); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = conn.ReadMessage()
+		if !IsCloseError(err, tc.code) {
+			t.Errorf("%s: err = %v, want close %d", tc.name, err, tc.code)
+		}
+		conn.Close()
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	url := newEchoServer(t, Upgrader{})
+	httpURL := "http" + strings.TrimPrefix(url, "ws")
+
+	resp, err := http.Get(httpURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET status = %d", resp.StatusCode)
+	}
+
+	_, resp, err = Dial(url, http.Header{"Origin": {"http://evil.example"}})
+	if !errors.Is(err, ErrBadHandshake) || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross origin: err = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, httpURL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Errorf("bad version: status = %d", resp.StatusCode)
+	}
+}
+
+func TestComputeAcceptKey(t *testing.T) {
+	// RFC 6455 1.3中的例子
+	if got := computeAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("accept key = %q", got)
+	}
+}